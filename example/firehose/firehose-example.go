@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli"
 	lazulidto "github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
@@ -11,7 +14,9 @@ import (
 
 func main() {
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	xrpcURL := os.Getenv("XRPC_URL")
 	wsURL := os.Getenv("WS_URL")
 
@@ -19,10 +24,31 @@ func main() {
 
 	handler := func(evt lazulidto.CommitEvent) error {
 		slog.Info("reading 1 firehose event", "type", evt.Type())
+
+		commit, ok := evt.(lazulidto.RepoCommitEvent)
+		if !ok || commit.TooBig {
+			return nil
+		}
+		records, err := commit.Records()
+		if err != nil {
+			slog.Warn("fail to read commit records", "repo", commit.Repo, "error", err)
+			return nil
+		}
+		for _, record := range records {
+			if post, isPost := record.Record.(lazulidto.PostRecord); isPost {
+				slog.Info("new post", "repo", commit.Repo, "rkey", record.RKey, "text", post.Text)
+			}
+		}
 		return nil
 	}
-	err := client.ConsumeFirehose(ctx, handler)
-	if err != nil {
+	err := client.ConsumeFirehose(ctx, handler,
+		lazuli.WithReconnect(lazuli.DefaultReconnectPolicy()),
+		lazuli.WithCursorStore(lazuli.NewFileCursorStore("firehose.cursor"), 100, 5*time.Second),
+		lazuli.WithOnDisconnect(func(err error) {
+			slog.Warn("firehose disconnected, reconnecting", "error", err)
+		}),
+	)
+	if err != nil && !errors.Is(err, context.Canceled) {
 		slog.Error("error consuming firehose", "error", err)
 		panic(err)
 	}
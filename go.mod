@@ -3,6 +3,7 @@ module github.com/augustoasilva/go-lazuli
 go 1.23
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
 	github.com/fxamacker/cbor/v2 v2.7.0
 	github.com/gorilla/websocket v1.5.3
 	github.com/stretchr/testify v1.9.0
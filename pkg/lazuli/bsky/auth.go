@@ -1,8 +1,9 @@
 package bsky
 
 type SessionRequest struct {
-	Identifier string `json:"identifier"`
-	Password   string `json:"password"`
+	Identifier      string `json:"identifier"`
+	Password        string `json:"password"`
+	AuthFactorToken string `json:"authFactorToken,omitempty"`
 }
 
 type AuthResponse struct {
@@ -15,4 +16,20 @@ type AuthResponse struct {
 	AccessJwt       string `json:"accessJwt"`
 	RefreshJwt      string `json:"refreshJwt"`
 	Active          bool   `json:"active"`
+	Status          string `json:"status,omitempty"`
+}
+
+// SessionResponse
+//
+// Represents the account status returned by com.atproto.server.getSession. Status holds the reason an account is
+// not active, such as "takendown", "suspended" or "deactivated".
+type SessionResponse struct {
+	DID             string `json:"did"`
+	DIDDoc          DIDDoc `json:"didDoc"`
+	Handle          string `json:"handle"`
+	Email           string `json:"email"`
+	EmailConfirmed  bool   `json:"emailConfirmed"`
+	EmailAuthFactor bool   `json:"emailAuthFactor"`
+	Active          bool   `json:"active"`
+	Status          string `json:"status,omitempty"`
 }
@@ -0,0 +1,12 @@
+package bsky
+
+import "time"
+
+// BlockRecord
+//
+// Represents a block of the account identified by the Subject DID.
+type BlockRecord struct {
+	LexiconTypeID string    `json:"$type"`
+	Subject       string    `json:"subject"` // did
+	CreatedAt     time.Time `json:"createdAt"`
+}
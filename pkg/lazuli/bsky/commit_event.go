@@ -10,5 +10,10 @@ type CommitEvent interface {
 type CommitEventType string
 
 const (
-	CommitEventTypeRepoCommit CommitEventType = "repo_commit"
+	CommitEventTypeRepoCommit    CommitEventType = "repo_commit"
+	CommitEventTypeRepoIdentity  CommitEventType = "repo_identity"
+	CommitEventTypeRepoAccount   CommitEventType = "repo_account"
+	CommitEventTypeRepoHandle    CommitEventType = "repo_handle"
+	CommitEventTypeRepoTombstone CommitEventType = "repo_tombstone"
+	CommitEventTypeRepoInfo      CommitEventType = "repo_info"
 )
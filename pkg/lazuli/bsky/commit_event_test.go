@@ -0,0 +1,65 @@
+package bsky
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitEvent_accessors(t *testing.T) {
+	handle := "alice.test"
+
+	tests := []struct {
+		name  string
+		event CommitEvent
+		typ   CommitEventType
+		repo  string
+		seq   int64
+	}{
+		{
+			name:  "Given an account event, When its accessors are called, Then they should return its type, DID and sequence",
+			event: RepoAccountEvent{Seq: 1, DID: "did:plc:alice", Active: false, Status: "takendown"},
+			typ:   CommitEventTypeRepoAccount,
+			repo:  "did:plc:alice",
+			seq:   1,
+		},
+		{
+			name:  "Given a handle event, When its accessors are called, Then they should return its type, DID and sequence",
+			event: RepoHandleEvent{Seq: 2, DID: "did:plc:alice", Handle: handle},
+			typ:   CommitEventTypeRepoHandle,
+			repo:  "did:plc:alice",
+			seq:   2,
+		},
+		{
+			name:  "Given an identity event, When its accessors are called, Then they should return its type, DID and sequence",
+			event: RepoIdentityEvent{Seq: 3, DID: "did:plc:alice", Handle: &handle},
+			typ:   CommitEventTypeRepoIdentity,
+			repo:  "did:plc:alice",
+			seq:   3,
+		},
+		{
+			name:  "Given a tombstone event, When its accessors are called, Then they should return its type, DID and sequence",
+			event: RepoTombstoneEvent{Seq: 4, DID: "did:plc:alice"},
+			typ:   CommitEventTypeRepoTombstone,
+			repo:  "did:plc:alice",
+			seq:   4,
+		},
+		{
+			name:  "Given an info event, When its accessors are called, Then it should return its type and no repository",
+			event: RepoInfoEvent{Name: "OutdatedCursor"},
+			typ:   CommitEventTypeRepoInfo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.typ, tt.event.Type())
+			assert.Equal(t, tt.repo, tt.event.GetRepo())
+			assert.Nil(t, tt.event.GetOps())
+			assert.Nil(t, tt.event.GetBlocks())
+			if event, ok := tt.event.(interface{ GetSeq() int64 }); ok {
+				assert.Equal(t, tt.seq, event.GetSeq())
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package bsky
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+)
+
 type DIDVerificationMethod struct {
 	ID                 string `json:"id"`
 	Type               string `json:"type"`
@@ -20,3 +27,63 @@ type DIDDoc struct {
 	VerificationMethod []DIDVerificationMethod `json:"verificationMethod"`
 	Service            []DIDService            `json:"service"`
 }
+
+// verification method types accepted for the atproto signing key
+const (
+	DIDVerificationMethodTypeMultikey = "Multikey"
+	DIDVerificationMethodTypeK256     = "EcdsaSecp256k1VerificationKey2019"
+	DIDVerificationMethodTypeP256     = "EcdsaSecp256r1VerificationKey2019"
+)
+
+// SigningKey returns the key the account signs its repository commits with, held by the "#atproto" verification
+// method of the document.
+func (d DIDDoc) SigningKey() (crypto.PublicKey, error) {
+	for _, method := range d.VerificationMethod {
+		if !d.isFragment(method.ID, "atproto") {
+			continue
+		}
+
+		switch method.Type {
+		case DIDVerificationMethodTypeMultikey:
+			return crypto.ParsePublicMultibase(method.PublicKeyMultibase)
+		case DIDVerificationMethodTypeK256:
+			return crypto.ParsePublicLegacyMultibase(crypto.CurveK256, method.PublicKeyMultibase)
+		case DIDVerificationMethodTypeP256:
+			return crypto.ParsePublicLegacyMultibase(crypto.CurveP256, method.PublicKeyMultibase)
+		default:
+			return nil, fmt.Errorf("unsupported verification method type %q", method.Type)
+		}
+	}
+
+	return nil, fmt.Errorf("did document %s has no atproto signing key", d.ID)
+}
+
+// DIDServiceTypePDS is the type of the service holding the PDS endpoint of an account.
+const DIDServiceTypePDS = "AtprotoPersonalDataServer"
+
+// PDSEndpoint returns the URL of the PDS hosting the account, held by the "#atproto_pds" service of the document, or
+// an empty string when there is none.
+func (d DIDDoc) PDSEndpoint() string {
+	for _, service := range d.Service {
+		if d.isFragment(service.ID, "atproto_pds") && service.Type == DIDServiceTypePDS {
+			return strings.TrimSuffix(service.ServiceEndpoint, "/")
+		}
+	}
+	return ""
+}
+
+// Handle returns the handle declared by the document, which is the first "at://" entry of AlsoKnownAs, or an empty
+// string when there is none. A declared handle is only trusted once it resolves back to the DID of the document.
+func (d DIDDoc) Handle() string {
+	for _, aka := range d.AlsoKnownAs {
+		if handle, ok := strings.CutPrefix(aka, "at://"); ok && handle != "" {
+			return strings.ToLower(handle)
+		}
+	}
+	return ""
+}
+
+// isFragment reports whether id references the given fragment of the document, either relatively or with the DID.
+func (d DIDDoc) isFragment(id, fragment string) bool {
+	return id == "#"+fragment || id == d.ID+"#"+fragment
+}
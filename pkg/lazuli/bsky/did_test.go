@@ -0,0 +1,82 @@
+package bsky
+
+import (
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDDoc(t *testing.T) {
+	const did = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+	const multikey = "zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w"
+
+	type out struct {
+		pds       string
+		handle    string
+		keyCurve  crypto.Curve
+		keyErrMsg string
+	}
+
+	tests := []struct {
+		name string
+		in   DIDDoc
+		out  out
+	}{
+		{
+			name: "Given a complete document, When its helpers are called, Then they should return the PDS, handle and signing key",
+			in: DIDDoc{
+				ID:          did,
+				AlsoKnownAs: []string{"https://example.com", "at://Alice.Example.com"},
+				VerificationMethod: []DIDVerificationMethod{
+					{ID: did + "#other", Type: "Unknown"},
+					{ID: did + "#atproto", Type: DIDVerificationMethodTypeMultikey, PublicKeyMultibase: multikey},
+				},
+				Service: []DIDService{
+					{ID: "#atproto_labeler", Type: "AtprotoLabeler", ServiceEndpoint: "https://labeler.example.com"},
+					{ID: did + "#atproto_pds", Type: DIDServiceTypePDS, ServiceEndpoint: "https://pds.example.com/"},
+				},
+			},
+			out: out{pds: "https://pds.example.com", handle: "alice.example.com", keyCurve: crypto.CurveK256},
+		},
+		{
+			name: "Given a document with a legacy P-256 key, When its signing key is read, Then it should return the key",
+			in: DIDDoc{
+				ID: did,
+				VerificationMethod: []DIDVerificationMethod{
+					{ID: "#atproto", Type: DIDVerificationMethodTypeP256, PublicKeyMultibase: "zxdM8dSstjrpZaRUwBmDvjGXweKuEMVN95A9oJBFjkWMh"},
+				},
+			},
+			out: out{keyCurve: crypto.CurveP256},
+		},
+		{
+			name: "Given a document with a key of an unknown type, When its signing key is read, Then it should return an error",
+			in: DIDDoc{
+				ID:                 did,
+				VerificationMethod: []DIDVerificationMethod{{ID: "#atproto", Type: "Ed25519VerificationKey2020"}},
+			},
+			out: out{keyErrMsg: `unsupported verification method type "Ed25519VerificationKey2020"`},
+		},
+		{
+			name: "Given an empty document, When its helpers are called, Then they should return nothing",
+			in:   DIDDoc{ID: did},
+			out:  out{keyErrMsg: "did document " + did + " has no atproto signing key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out.pds, tt.in.PDSEndpoint())
+			assert.Equal(t, tt.out.handle, tt.in.Handle())
+
+			key, err := tt.in.SigningKey()
+			if tt.out.keyErrMsg != "" {
+				assert.EqualError(t, err, tt.out.keyErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.keyCurve, key.Curve())
+		})
+	}
+}
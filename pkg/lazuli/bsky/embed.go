@@ -1,12 +1,24 @@
 package bsky
 
+import "github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+
 type EmbedRecord struct {
 	LexiconTypeID string `json:"$type"`
 	Record        Record `json:"record"`
 }
 
 type BlobRef struct {
-	Link string `json:"$link"`
+	Link cid.CID `json:"$link"`
+}
+
+// MarshalCBOR encodes the DAG-CBOR form of a blob reference, which is a link instead of a map.
+func (r BlobRef) MarshalCBOR() ([]byte, error) {
+	return r.Link.MarshalCBOR()
+}
+
+// UnmarshalCBOR decodes the DAG-CBOR form of a blob reference, which is a link instead of a map.
+func (r *BlobRef) UnmarshalCBOR(data []byte) error {
+	return r.Link.UnmarshalCBOR(data)
 }
 
 type BlobRecord struct {
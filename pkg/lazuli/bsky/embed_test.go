@@ -0,0 +1,26 @@
+package bsky
+
+import (
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobRef_CBOR(t *testing.T) {
+	t.Run("Given a blob reference, When it is encoded to CBOR and decoded back, Then it should be a link to the same CID", func(t *testing.T) {
+		ref := BlobRef{Link: cid.Sum(cid.CodecRaw, []byte("image"))}
+
+		b, err := cbor.Marshal(ref)
+		require.NoError(t, err)
+		linkBytes, err := ref.Link.MarshalCBOR()
+		require.NoError(t, err)
+		var got BlobRef
+		require.NoError(t, cbor.Unmarshal(b, &got))
+
+		assert.Equal(t, linkBytes, b)
+		assert.Equal(t, ref, got)
+	})
+}
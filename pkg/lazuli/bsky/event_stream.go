@@ -0,0 +1,24 @@
+package bsky
+
+// EventStreamHeader
+//
+// Represents the header that precedes the body of every frame of an event stream such as
+// com.atproto.sync.subscribeRepos. Op is 1 for messages, with Type holding the message kind (e.g. "#commit"), and
+// -1 for errors.
+type EventStreamHeader struct {
+	Op   int64  `cbor:"op"`
+	Type string `cbor:"t,omitempty"`
+}
+
+const (
+	EventStreamOpMessage int64 = 1
+	EventStreamOpError   int64 = -1
+)
+
+// EventStreamError
+//
+// Represents the body of an error frame, sent right before the server closes the stream.
+type EventStreamError struct {
+	Error   string `cbor:"error"`
+	Message string `cbor:"message,omitempty"`
+}
@@ -0,0 +1,12 @@
+package bsky
+
+import "time"
+
+// FollowRecord
+//
+// Represents a follow of the account identified by the Subject DID.
+type FollowRecord struct {
+	LexiconTypeID string    `json:"$type"`
+	Subject       string    `json:"subject"` // did
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -1,6 +1,10 @@
 package bsky
 
-import "time"
+import (
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+)
 
 // PostAuthor
 //
@@ -30,11 +34,11 @@ type PostViewer struct {
 // Represents the post record data.
 type PostRecord struct {
 	LexiconTypeID string           `json:"$type"`
-	URI           string           `json:"uri"`
+	URI           string           `json:"uri,omitempty"`
 	CreatedAt     time.Time        `json:"createdAt"`
-	Embed         any              `json:"embed"` // TODO: embed can be many types of objects, for now it will be any, need improvement
+	Embed         any              `json:"embed,omitempty"` // TODO: embed can be many types of objects, for now it will be any, need improvement
 	Facets        []map[string]any `json:"facets,omitempty"`
-	Langs         []string         `json:"langs"`
+	Langs         []string         `json:"langs,omitempty"`
 	Reply         *Reply           `json:"reply,omitempty"`
 	Text          string           `json:"text"`
 }
@@ -45,7 +49,7 @@ type PostRecord struct {
 type Post struct {
 	LexiconTypeID string     `json:"$type"`
 	URI           string     `json:"uri"` // at-uri
-	CID           any        `json:"cid"` // TODO: for now handle CID like the the firehose, but need improvement
+	CID           cid.CID    `json:"cid"`
 	Author        PostAuthor `json:"author"`
 	Record        PostRecord `json:"record"`
 	Embed         any        `json:"embed"` // TODO: embed can be many types of objects, for now it will be any, need improvement
@@ -0,0 +1,16 @@
+package bsky
+
+import "time"
+
+// ProfileRecord
+//
+// Represents the profile of an account, which is always stored with the "self" record key.
+type ProfileRecord struct {
+	LexiconTypeID string         `json:"$type"`
+	DisplayName   string         `json:"displayName,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Avatar        *BlobRecord    `json:"avatar,omitempty"`
+	Banner        *BlobRecord    `json:"banner,omitempty"`
+	PinnedPost    *RepoStrongRef `json:"pinnedPost,omitempty"`
+	CreatedAt     *time.Time     `json:"createdAt,omitempty"`
+}
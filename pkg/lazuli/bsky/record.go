@@ -1,18 +1,30 @@
 package bsky
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+)
 
 type Record struct {
-	CID any    `json:"cid"`
-	URI string `json:"uri"`
+	CID cid.CID `json:"cid"`
+	URI string  `json:"uri"`
 }
 
+// RequestRecord
+//
+// Deprecated: records are created with Client.CreateRecord and the record type of their collection, such as
+// PostRecord or LikeRecord.
 type RequestRecord struct {
 	Subject   RepoStrongRef `json:"subject"`
 	Text      string        `json:"text,omitempty"`
 	CreatedAt time.Time     `json:"createdAt"`
 }
 
+// RequestRecordBody
+//
+// Deprecated: the body of com.atproto.repo.createRecord is WriteRecordRequest.
 type RequestRecordBody struct {
 	LexiconTypeID string        `json:"$type"`
 	Collection    string        `json:"collection"`
@@ -30,3 +42,142 @@ type CreateRecordParams struct {
 	URI      string
 	CID      string
 }
+
+// validation statuses of a record written to a repository
+const (
+	ValidationStatusValid   = "valid"
+	ValidationStatusUnknown = "unknown"
+)
+
+// CommitMeta
+//
+// Represents the repository commit that applied a write.
+type CommitMeta struct {
+	CID cid.CID `json:"cid"`
+	Rev string  `json:"rev"`
+}
+
+// CreateRecordResponse
+//
+// Represents the response of com.atproto.repo.createRecord, holding the URI and CID needed to reference the new
+// record.
+type CreateRecordResponse struct {
+	URI              string      `json:"uri"`
+	CID              cid.CID     `json:"cid"`
+	Commit           *CommitMeta `json:"commit,omitempty"`
+	ValidationStatus string      `json:"validationStatus,omitempty"`
+}
+
+// WriteRecordRequest
+//
+// Represents the body of the com.atproto.repo calls writing a record to the repository of the session. SwapRecord and
+// SwapCommit make the write fail with InvalidSwap when the record or the repository changed since they were read.
+type WriteRecordRequest struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey,omitempty"`
+	Validate   *bool  `json:"validate,omitempty"`
+	Record     any    `json:"record,omitempty"`
+	SwapRecord string `json:"swapRecord,omitempty"`
+	SwapCommit string `json:"swapCommit,omitempty"`
+}
+
+// PutRecordResponse
+//
+// Represents the response of com.atproto.repo.putRecord.
+type PutRecordResponse struct {
+	URI              string      `json:"uri"`
+	CID              cid.CID     `json:"cid"`
+	Commit           *CommitMeta `json:"commit,omitempty"`
+	ValidationStatus string      `json:"validationStatus,omitempty"`
+}
+
+// DeleteRecordResponse
+//
+// Represents the response of com.atproto.repo.deleteRecord. Commit is nil when there was no record to delete.
+type DeleteRecordResponse struct {
+	Commit *CommitMeta `json:"commit,omitempty"`
+}
+
+// RecordValue
+//
+// Represents a record read with com.atproto.repo.getRecord or com.atproto.repo.listRecords. Value holds the JSON form
+// of the record, which can be unmarshaled into the type of its collection, such as PostRecord.
+type RecordValue struct {
+	URI   string          `json:"uri"`
+	CID   cid.CID         `json:"cid"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ListRecordsResponse
+//
+// Represents a page of com.atproto.repo.listRecords. Cursor is empty on the last page.
+type ListRecordsResponse struct {
+	Cursor  string        `json:"cursor,omitempty"`
+	Records []RecordValue `json:"records"`
+}
+
+// types of the writes and results of com.atproto.repo.applyWrites
+const (
+	WriteTypeCreate       = "com.atproto.repo.applyWrites#create"
+	WriteTypeUpdate       = "com.atproto.repo.applyWrites#update"
+	WriteTypeDelete       = "com.atproto.repo.applyWrites#delete"
+	WriteResultTypeCreate = "com.atproto.repo.applyWrites#createResult"
+	WriteResultTypeUpdate = "com.atproto.repo.applyWrites#updateResult"
+	WriteResultTypeDelete = "com.atproto.repo.applyWrites#deleteResult"
+)
+
+// WriteOp
+//
+// Represents a write of com.atproto.repo.applyWrites, built with NewCreateWriteOp, NewUpdateWriteOp or
+// NewDeleteWriteOp.
+type WriteOp struct {
+	Type       string `json:"$type"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey,omitempty"`
+	Value      any    `json:"value,omitempty"`
+}
+
+// NewCreateWriteOp creates value in collection, under a record key generated by the server unless RKey is set.
+func NewCreateWriteOp(collection string, value any) WriteOp {
+	return WriteOp{Type: WriteTypeCreate, Collection: collection, Value: value}
+}
+
+// NewUpdateWriteOp replaces the record of collection at rkey with value.
+func NewUpdateWriteOp(collection, rkey string, value any) WriteOp {
+	return WriteOp{Type: WriteTypeUpdate, Collection: collection, RKey: rkey, Value: value}
+}
+
+// NewDeleteWriteOp deletes the record of collection at rkey.
+func NewDeleteWriteOp(collection, rkey string) WriteOp {
+	return WriteOp{Type: WriteTypeDelete, Collection: collection, RKey: rkey}
+}
+
+// ApplyWritesRequest
+//
+// Represents the body of com.atproto.repo.applyWrites.
+type ApplyWritesRequest struct {
+	Repo       string    `json:"repo"`
+	Validate   *bool     `json:"validate,omitempty"`
+	Writes     []WriteOp `json:"writes"`
+	SwapCommit string    `json:"swapCommit,omitempty"`
+}
+
+// WriteResult
+//
+// Represents the result of a WriteOp. URI, CID and ValidationStatus are empty for deletions.
+type WriteResult struct {
+	Type             string  `json:"$type"`
+	URI              string  `json:"uri,omitempty"`
+	CID              cid.CID `json:"cid"`
+	ValidationStatus string  `json:"validationStatus,omitempty"`
+}
+
+// ApplyWritesResponse
+//
+// Represents the response of com.atproto.repo.applyWrites, with one result for each write, in the order of the
+// writes.
+type ApplyWritesResponse struct {
+	Commit  *CommitMeta   `json:"commit,omitempty"`
+	Results []WriteResult `json:"results,omitempty"`
+}
@@ -0,0 +1,87 @@
+package bsky
+
+import (
+	"reflect"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	CollectionPost    = "app.bsky.feed.post"
+	CollectionLike    = "app.bsky.feed.like"
+	CollectionRepost  = "app.bsky.feed.repost"
+	CollectionFollow  = "app.bsky.graph.follow"
+	CollectionBlock   = "app.bsky.graph.block"
+	CollectionProfile = "app.bsky.actor.profile"
+)
+
+// recordDecMode decodes untyped maps as map[string]any, which is what records use as keys.
+var recordDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]any(nil)),
+}.DecMode()
+
+// UnknownRecord
+//
+// Represents a record of a collection without a type in lazuli, or that could not be decoded into its type, holding
+// the raw record.
+type UnknownRecord struct {
+	LexiconTypeID string
+	Data          map[string]any
+}
+
+// OperationRecord
+//
+// Represents an operation of a commit along with the record it created or updated. Record is nil for deletions, and
+// otherwise holds a PostRecord, LikeRecord, RepostRecord, FollowRecord, BlockRecord, ProfileRecord or UnknownRecord.
+type OperationRecord struct {
+	Operation  RepoOperation
+	Collection string
+	RKey       string
+	CID        cid.CID
+	Record     any
+}
+
+// DecodeRecord decodes a DAG-CBOR record into the type of its collection. Records of other collections, or that do
+// not match the type of their collection, are returned as UnknownRecord.
+func DecodeRecord(collection string, data []byte) (any, error) {
+	var record any
+	var err error
+	switch collection {
+	case CollectionPost:
+		record, err = decodeRecord[PostRecord](data)
+	case CollectionLike:
+		record, err = decodeRecord[LikeRecord](data)
+	case CollectionRepost:
+		record, err = decodeRecord[RepostRecord](data)
+	case CollectionFollow:
+		record, err = decodeRecord[FollowRecord](data)
+	case CollectionBlock:
+		record, err = decodeRecord[BlockRecord](data)
+	case CollectionProfile:
+		record, err = decodeRecord[ProfileRecord](data)
+	default:
+		return decodeUnknownRecord(data)
+	}
+	if err != nil {
+		return decodeUnknownRecord(data)
+	}
+	return record, nil
+}
+
+func decodeRecord[T any](data []byte) (any, error) {
+	var record T
+	if err := recordDecMode.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func decodeUnknownRecord(data []byte) (any, error) {
+	var raw map[string]any
+	if err := recordDecMode.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	lexiconTypeID, _ := raw["$type"].(string)
+	return UnknownRecord{LexiconTypeID: lexiconTypeID, Data: raw}, nil
+}
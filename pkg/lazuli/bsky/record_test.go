@@ -0,0 +1,39 @@
+package bsky
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteOp_builders(t *testing.T) {
+	record := ProfileRecord{DisplayName: "Test"}
+
+	tests := []struct {
+		name string
+		in   WriteOp
+		out  WriteOp
+	}{
+		{
+			name: "Given a record, When NewCreateWriteOp is called, Then it should create it without a record key",
+			in:   NewCreateWriteOp(CollectionProfile, record),
+			out:  WriteOp{Type: WriteTypeCreate, Collection: CollectionProfile, Value: record},
+		},
+		{
+			name: "Given a record and a key, When NewUpdateWriteOp is called, Then it should replace the record at the key",
+			in:   NewUpdateWriteOp(CollectionProfile, "self", record),
+			out:  WriteOp{Type: WriteTypeUpdate, Collection: CollectionProfile, RKey: "self", Value: record},
+		},
+		{
+			name: "Given a key, When NewDeleteWriteOp is called, Then it should delete the record at the key",
+			in:   NewDeleteWriteOp(CollectionProfile, "self"),
+			out:  WriteOp{Type: WriteTypeDelete, Collection: CollectionProfile, RKey: "self"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out, tt.in)
+		})
+	}
+}
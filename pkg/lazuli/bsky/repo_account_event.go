@@ -0,0 +1,33 @@
+package bsky
+
+// RepoAccountEvent
+//
+// Represents a change to the hosting status of an account. When Active is false, Status holds the reason, such as
+// "takendown", "suspended", "deleted" or "deactivated".
+type RepoAccountEvent struct {
+	Seq    int64  `cbor:"seq"`
+	DID    string `cbor:"did"`
+	Time   string `cbor:"time"`
+	Active bool   `cbor:"active"`
+	Status string `cbor:"status,omitempty"`
+}
+
+func (e RepoAccountEvent) Type() CommitEventType {
+	return CommitEventTypeRepoAccount
+}
+
+func (e RepoAccountEvent) GetSeq() int64 {
+	return e.Seq
+}
+
+func (e RepoAccountEvent) GetRepo() string {
+	return e.DID
+}
+
+func (e RepoAccountEvent) GetOps() []RepoOperation {
+	return nil
+}
+
+func (e RepoAccountEvent) GetBlocks() []byte {
+	return nil
+}
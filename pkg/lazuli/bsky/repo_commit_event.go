@@ -1,5 +1,14 @@
 package bsky
 
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+)
+
 type RepoCommitEvent struct {
 	Repo   string          `cbor:"repo"`
 	Rev    string          `cbor:"rev"`
@@ -7,17 +16,21 @@ type RepoCommitEvent struct {
 	Since  string          `cbor:"since"`
 	Time   string          `cbor:"time"`
 	TooBig bool            `cbor:"tooBig"`
-	Prev   any             `cbor:"prev"`
+	Prev   cid.CID         `cbor:"prev"`
 	Rebase bool            `cbor:"rebase"`
 	Blocks []byte          `cbor:"blocks"`
 	Ops    []RepoOperation `cbor:"ops"`
-	Commit any             `json:"commit"` // Repo commit object CID
+	Commit cid.CID         `cbor:"commit"` // Repo commit object CID
 }
 
 func (e RepoCommitEvent) Type() CommitEventType {
 	return CommitEventTypeRepoCommit
 }
 
+func (e RepoCommitEvent) GetSeq() int64 {
+	return e.Seq
+}
+
 func (e RepoCommitEvent) GetRepo() string {
 	return e.Repo
 }
@@ -30,10 +43,55 @@ func (e RepoCommitEvent) GetBlocks() []byte {
 	return e.Blocks
 }
 
+// ReadBlocks decodes Blocks, a CAR file holding the commit and the records created or updated by its operations.
+func (e RepoCommitEvent) ReadBlocks() (*car.Archive, error) {
+	return car.ReadAll(bytes.NewReader(e.Blocks))
+}
+
+const (
+	RepoOperationActionCreate = "create"
+	RepoOperationActionUpdate = "update"
+	RepoOperationActionDelete = "delete"
+)
+
 type RepoOperation struct {
-	Action string `cbor:"action"`
-	Path   string `cbor:"path"`
-	Reply  *Reply `cbor:"reply,omitempty"`
-	Text   []byte `cbor:"text"`
-	CID    any    `cbor:"cid"`
+	Action string  `cbor:"action"`
+	Path   string  `cbor:"path"` // collection/rkey
+	Reply  *Reply  `cbor:"reply,omitempty"`
+	CID    cid.CID `cbor:"cid"` // undefined for deletions
+}
+
+// Records returns the operations of the commit along with the records they created or updated, decoded from Blocks.
+func (e RepoCommitEvent) Records() ([]OperationRecord, error) {
+	var blocks *car.Archive
+	records := make([]OperationRecord, 0, len(e.Ops))
+	for _, op := range e.Ops {
+		collection, rkey, _ := strings.Cut(op.Path, "/")
+		record := OperationRecord{Operation: op, Collection: collection, RKey: rkey}
+		if op.Action == RepoOperationActionDelete {
+			records = append(records, record)
+			continue
+		}
+
+		if blocks == nil {
+			var err error
+			if blocks, err = e.ReadBlocks(); err != nil {
+				return nil, err
+			}
+		}
+
+		data, ok := blocks.Get(op.CID)
+		if !ok {
+			return nil, fmt.Errorf("record %s of %s not found in commit blocks", op.CID, op.Path)
+		}
+
+		var err error
+		record.CID = op.CID
+		if record.Record, err = DecodeRecord(collection, data); err != nil {
+			return nil, fmt.Errorf("fail to decode record %s: %w", op.Path, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
 }
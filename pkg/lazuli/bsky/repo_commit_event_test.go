@@ -0,0 +1,158 @@
+package bsky
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func link(c cid.CID) cbor.Tag {
+	return cbor.Tag{Number: 42, Content: append([]byte{0x00}, c.Bytes()...)}
+}
+
+// newCommitEvent builds a commit event with a create operation for each record, keyed by path.
+func newCommitEvent(t *testing.T, records map[string]map[string]any, deletes ...string) (RepoCommitEvent, map[string]cid.CID) {
+	var buf bytes.Buffer
+	writer, err := car.NewWriter(&buf)
+	require.NoError(t, err)
+
+	evt := RepoCommitEvent{Repo: "did:plc:test"}
+	cids := make(map[string]cid.CID)
+	for path, record := range records {
+		data, err := cbor.Marshal(record)
+		require.NoError(t, err)
+		c := cid.Sum(cid.CodecDagCBOR, data)
+		require.NoError(t, writer.Write(car.Block{CID: c, Data: data}))
+
+		cids[path] = c
+		evt.Ops = append(evt.Ops, RepoOperation{Action: RepoOperationActionCreate, Path: path, CID: c})
+	}
+	for _, path := range deletes {
+		evt.Ops = append(evt.Ops, RepoOperation{Action: RepoOperationActionDelete, Path: path})
+	}
+	evt.Blocks = buf.Bytes()
+
+	return evt, cids
+}
+
+func TestRepoCommitEvent_Records(t *testing.T) {
+	createdAt := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	avatar := cid.Sum(cid.CodecRaw, []byte("avatar"))
+
+	tests := []struct {
+		name   string
+		path   string
+		record map[string]any
+		out    any
+	}{
+		{
+			name: "Given a post operation, When Records is called, Then it should return a PostRecord",
+			path: "app.bsky.feed.post/3kpost",
+			record: map[string]any{
+				"$type":     CollectionPost,
+				"text":      "hello world",
+				"langs":     []string{"en"},
+				"createdAt": "2024-10-01T12:00:00.000Z",
+				"facets":    []any{map[string]any{"index": map[string]any{"byteStart": 0, "byteEnd": 5}}},
+			},
+			out: PostRecord{
+				LexiconTypeID: CollectionPost,
+				Text:          "hello world",
+				Langs:         []string{"en"},
+				CreatedAt:     createdAt,
+				Facets:        []map[string]any{{"index": map[string]any{"byteStart": uint64(0), "byteEnd": uint64(5)}}},
+			},
+		},
+		{
+			name: "Given a like operation, When Records is called, Then it should return a LikeRecord",
+			path: "app.bsky.feed.like/3klike",
+			record: map[string]any{
+				"$type":     CollectionLike,
+				"subject":   map[string]any{"uri": "at://did:plc:other/app.bsky.feed.post/3k", "cid": "bafyrei"},
+				"createdAt": "2024-10-01T12:00:00Z",
+			},
+			out: LikeRecord{
+				LexiconTypeID: CollectionLike,
+				Subject:       RepoStrongRef{URI: "at://did:plc:other/app.bsky.feed.post/3k", CID: "bafyrei"},
+				CreatedAt:     createdAt,
+			},
+		},
+		{
+			name: "Given a follow operation, When Records is called, Then it should return a FollowRecord",
+			path: "app.bsky.graph.follow/3kfollow",
+			record: map[string]any{
+				"$type":     CollectionFollow,
+				"subject":   "did:plc:other",
+				"createdAt": "2024-10-01T12:00:00Z",
+			},
+			out: FollowRecord{LexiconTypeID: CollectionFollow, Subject: "did:plc:other", CreatedAt: createdAt},
+		},
+		{
+			name: "Given a profile operation, When Records is called, Then it should return a ProfileRecord with its blobs",
+			path: "app.bsky.actor.profile/self",
+			record: map[string]any{
+				"$type":       CollectionProfile,
+				"displayName": "Test",
+				"avatar":      map[string]any{"$type": "blob", "ref": link(avatar), "mimeType": "image/png", "size": 10},
+			},
+			out: ProfileRecord{
+				LexiconTypeID: CollectionProfile,
+				DisplayName:   "Test",
+				Avatar:        &BlobRecord{LexiconTypeID: "blob", Ref: BlobRef{Link: avatar}, MimeType: "image/png", Size: 10},
+			},
+		},
+		{
+			name:   "Given an operation of an unknown collection, When Records is called, Then it should return an UnknownRecord",
+			path:   "com.example.record/3kcustom",
+			record: map[string]any{"$type": "com.example.record", "value": "custom"},
+			out:    UnknownRecord{LexiconTypeID: "com.example.record", Data: map[string]any{"$type": "com.example.record", "value": "custom"}},
+		},
+		{
+			name:   "Given a record that does not match its collection type, When Records is called, Then it should return an UnknownRecord",
+			path:   "app.bsky.feed.post/3kinvalid",
+			record: map[string]any{"$type": CollectionPost, "text": "hello", "createdAt": "yesterday"},
+			out:    UnknownRecord{LexiconTypeID: CollectionPost, Data: map[string]any{"$type": CollectionPost, "text": "hello", "createdAt": "yesterday"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, cids := newCommitEvent(t, map[string]map[string]any{tt.path: tt.record})
+
+			records, err := evt.Records()
+
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			assert.Equal(t, cids[tt.path], records[0].CID)
+			assert.Equal(t, tt.out, records[0].Record)
+		})
+	}
+}
+
+func TestRepoCommitEvent_Records_operations(t *testing.T) {
+	t.Run("Given a delete operation, When Records is called, Then it should return it without a record", func(t *testing.T) {
+		evt := RepoCommitEvent{Ops: []RepoOperation{{Action: RepoOperationActionDelete, Path: "app.bsky.feed.like/3klike"}}}
+
+		records, err := evt.Records()
+
+		require.NoError(t, err)
+		assert.Equal(t, []OperationRecord{{Operation: evt.Ops[0], Collection: CollectionLike, RKey: "3klike"}}, records)
+	})
+
+	t.Run("Given an operation without its block, When Records is called, Then it should return an error", func(t *testing.T) {
+		evt, _ := newCommitEvent(t, nil)
+		missing := cid.Sum(cid.CodecDagCBOR, []byte("missing"))
+		evt.Ops = []RepoOperation{{Action: RepoOperationActionCreate, Path: "app.bsky.feed.post/3k", CID: missing}}
+
+		records, err := evt.Records()
+
+		assert.Nil(t, records)
+		assert.EqualError(t, err, "record "+missing.String()+" of app.bsky.feed.post/3k not found in commit blocks")
+	})
+}
@@ -0,0 +1,32 @@
+package bsky
+
+// RepoHandleEvent
+//
+// Represents a handle change of an account. It is deprecated in favor of RepoIdentityEvent, but still sent by some
+// servers.
+type RepoHandleEvent struct {
+	Seq    int64  `cbor:"seq"`
+	DID    string `cbor:"did"`
+	Handle string `cbor:"handle"`
+	Time   string `cbor:"time"`
+}
+
+func (e RepoHandleEvent) Type() CommitEventType {
+	return CommitEventTypeRepoHandle
+}
+
+func (e RepoHandleEvent) GetSeq() int64 {
+	return e.Seq
+}
+
+func (e RepoHandleEvent) GetRepo() string {
+	return e.DID
+}
+
+func (e RepoHandleEvent) GetOps() []RepoOperation {
+	return nil
+}
+
+func (e RepoHandleEvent) GetBlocks() []byte {
+	return nil
+}
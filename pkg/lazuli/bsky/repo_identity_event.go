@@ -0,0 +1,32 @@
+package bsky
+
+// RepoIdentityEvent
+//
+// Represents a change to the identity of an account, such as its handle or DID document, which should be resolved
+// again.
+type RepoIdentityEvent struct {
+	Seq    int64   `cbor:"seq"`
+	DID    string  `cbor:"did"`
+	Time   string  `cbor:"time"`
+	Handle *string `cbor:"handle,omitempty"`
+}
+
+func (e RepoIdentityEvent) Type() CommitEventType {
+	return CommitEventTypeRepoIdentity
+}
+
+func (e RepoIdentityEvent) GetSeq() int64 {
+	return e.Seq
+}
+
+func (e RepoIdentityEvent) GetRepo() string {
+	return e.DID
+}
+
+func (e RepoIdentityEvent) GetOps() []RepoOperation {
+	return nil
+}
+
+func (e RepoIdentityEvent) GetBlocks() []byte {
+	return nil
+}
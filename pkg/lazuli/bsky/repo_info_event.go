@@ -0,0 +1,26 @@
+package bsky
+
+// RepoInfoEvent
+//
+// Represents an informational message about the stream itself, such as "OutdatedCursor" when the requested cursor
+// is older than the events kept by the server.
+type RepoInfoEvent struct {
+	Name    string `cbor:"name"`
+	Message string `cbor:"message,omitempty"`
+}
+
+func (e RepoInfoEvent) Type() CommitEventType {
+	return CommitEventTypeRepoInfo
+}
+
+func (e RepoInfoEvent) GetRepo() string {
+	return ""
+}
+
+func (e RepoInfoEvent) GetOps() []RepoOperation {
+	return nil
+}
+
+func (e RepoInfoEvent) GetBlocks() []byte {
+	return nil
+}
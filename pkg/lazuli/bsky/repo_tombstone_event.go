@@ -0,0 +1,31 @@
+package bsky
+
+// RepoTombstoneEvent
+//
+// Represents the deletion of an account. It is deprecated in favor of RepoAccountEvent, but still sent by some
+// servers.
+type RepoTombstoneEvent struct {
+	Seq  int64  `cbor:"seq"`
+	DID  string `cbor:"did"`
+	Time string `cbor:"time"`
+}
+
+func (e RepoTombstoneEvent) Type() CommitEventType {
+	return CommitEventTypeRepoTombstone
+}
+
+func (e RepoTombstoneEvent) GetSeq() int64 {
+	return e.Seq
+}
+
+func (e RepoTombstoneEvent) GetRepo() string {
+	return e.DID
+}
+
+func (e RepoTombstoneEvent) GetOps() []RepoOperation {
+	return nil
+}
+
+func (e RepoTombstoneEvent) GetBlocks() []byte {
+	return nil
+}
@@ -0,0 +1,34 @@
+package bsky
+
+import "github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+
+// RepoRecord
+//
+// Represents a record of a repository export. Record holds a PostRecord, LikeRecord, RepostRecord, FollowRecord,
+// BlockRecord, ProfileRecord or UnknownRecord.
+type RepoRecord struct {
+	Collection string
+	RKey       string
+	CID        cid.CID
+	Record     any
+}
+
+// ListedRepo
+//
+// Represents a repository listed by com.atproto.sync.listRepos. Head is the CID of the current commit of the
+// repository. Active is nil when the server does not report it, and Status holds the reason an account is not active.
+type ListedRepo struct {
+	DID    string  `json:"did"`
+	Head   cid.CID `json:"head"`
+	Rev    string  `json:"rev"`
+	Active *bool   `json:"active,omitempty"`
+	Status string  `json:"status,omitempty"`
+}
+
+// ListReposResponse
+//
+// Represents a page of com.atproto.sync.listRepos. Cursor is empty on the last page.
+type ListReposResponse struct {
+	Cursor string       `json:"cursor,omitempty"`
+	Repos  []ListedRepo `json:"repos"`
+}
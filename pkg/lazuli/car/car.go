@@ -0,0 +1,186 @@
+// Package car reads CAR (Content Addressable aRchive) v1 files, the format used by the AT Protocol to transfer
+// repository blocks, such as the blocks of firehose commits and the repository exports of com.atproto.sync.getRepo.
+package car
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// MaxSectionSize is the largest header or block accepted, protecting readers from allocating huge buffers because
+// of a malformed length.
+const MaxSectionSize = 8 << 20
+
+// Header is the header of a CAR v1 file, listing its root CIDs.
+type Header struct {
+	Version uint64    `cbor:"version"`
+	Roots   []cid.CID `cbor:"roots"`
+}
+
+// Block is a block of data and the CID identifying it.
+type Block struct {
+	CID  cid.CID
+	Data []byte
+}
+
+// Reader reads the blocks of a CAR file one at a time.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+}
+
+// NewReader reads the header of the CAR file from r, returning a reader positioned at its first block.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	section, err := reader.readSection()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("car: fail to read header: %w", err)
+	}
+
+	if err := cbor.Unmarshal(section, &reader.header); err != nil {
+		return nil, fmt.Errorf("car: fail to decode header: %w", err)
+	}
+	if reader.header.Version != 1 {
+		return nil, fmt.Errorf("car: unsupported version %d", reader.header.Version)
+	}
+
+	return reader, nil
+}
+
+// Header returns the header of the CAR file.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next block of the CAR file, or io.EOF when there are no more blocks. Blocks hashed with SHA-256
+// are checked against their CID.
+func (r *Reader) Next() (Block, error) {
+	section, err := r.readSection()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Block{}, io.EOF
+		}
+		return Block{}, fmt.Errorf("car: fail to read block: %w", err)
+	}
+
+	c, n, err := cid.Decode(section)
+	if err != nil {
+		return Block{}, fmt.Errorf("car: fail to decode block cid: %w", err)
+	}
+
+	block := Block{CID: c, Data: section[n:]}
+	if c.HashCode() == cid.HashSHA256 && !c.Matches(block.Data) {
+		return Block{}, fmt.Errorf("car: block does not match its cid %s", c)
+	}
+
+	return block, nil
+}
+
+// readSection reads a section prefixed by its length. It returns io.EOF only when there is no section left.
+func (r *Reader) readSection() ([]byte, error) {
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if length > MaxSectionSize {
+		return nil, fmt.Errorf("section of %d bytes is larger than the maximum of %d bytes", length, MaxSectionSize)
+	}
+
+	section := make([]byte, length)
+	if _, err := io.ReadFull(r.r, section); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return section, nil
+}
+
+// Archive holds every block of a CAR file in memory, keyed by CID.
+type Archive struct {
+	Roots  []cid.CID
+	blocks map[cid.CID][]byte
+}
+
+// ReadAll reads a whole CAR file into an Archive.
+func ReadAll(r io.Reader) (*Archive, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		Roots:  reader.Header().Roots,
+		blocks: make(map[cid.CID][]byte),
+	}
+	for {
+		block, err := reader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return archive, nil
+			}
+			return nil, err
+		}
+		archive.blocks[block.CID] = block.Data
+	}
+}
+
+// Get returns the data of the block identified by c.
+func (a *Archive) Get(c cid.CID) ([]byte, bool) {
+	data, ok := a.blocks[c]
+	return data, ok
+}
+
+// Len returns the number of blocks in the archive.
+func (a *Archive) Len() int {
+	return len(a.blocks)
+}
+
+// Writer writes a CAR v1 file one block at a time.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the header of a CAR file with the given roots to w, returning a writer for its blocks.
+func NewWriter(w io.Writer, roots ...cid.CID) (*Writer, error) {
+	header, err := cid.DagCBOR.Marshal(Header{Version: 1, Roots: roots})
+	if err != nil {
+		return nil, fmt.Errorf("car: fail to encode header: %w", err)
+	}
+
+	writer := &Writer{w: w}
+	if err := writer.writeSection(header); err != nil {
+		return nil, fmt.Errorf("car: fail to write header: %w", err)
+	}
+	return writer, nil
+}
+
+// Write appends a block to the CAR file.
+func (w *Writer) Write(block Block) error {
+	if err := w.writeSection(append(block.CID.Bytes(), block.Data...)); err != nil {
+		return fmt.Errorf("car: fail to write block: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) writeSection(section []byte) error {
+	if _, err := w.w.Write(binary.AppendUvarint(nil, uint64(len(section)))); err != nil {
+		return err
+	}
+	_, err := w.w.Write(section)
+	return err
+}
@@ -0,0 +1,190 @@
+package car
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildCAR encodes a CAR v1 file with the given roots and blocks.
+func buildCAR(t *testing.T, version uint64, roots []cid.CID, blocks ...Block) []byte {
+	links := make([]cbor.Tag, 0, len(roots))
+	for _, root := range roots {
+		links = append(links, cbor.Tag{Number: 42, Content: append([]byte{0x00}, root.Bytes()...)})
+	}
+	header, err := cbor.Marshal(map[string]any{"version": version, "roots": links})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	buf.Write(binary.AppendUvarint(nil, uint64(len(header))))
+	buf.Write(header)
+	for _, block := range blocks {
+		section := append(block.CID.Bytes(), block.Data...)
+		buf.Write(binary.AppendUvarint(nil, uint64(len(section))))
+		buf.Write(section)
+	}
+	return buf.Bytes()
+}
+
+func newBlock(data string) Block {
+	return Block{CID: cid.Sum(cid.CodecDagCBOR, []byte(data)), Data: []byte(data)}
+}
+
+func TestReader(t *testing.T) {
+	first, second := newBlock("first"), newBlock("second")
+
+	type out struct {
+		header Header
+		blocks []Block
+		err    string
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		out  out
+	}{
+		{
+			name: "Given a valid CAR file, When it is read, Then it should return the header and every block in order",
+			in:   buildCAR(t, 1, []cid.CID{first.CID}, first, second),
+			out: out{
+				header: Header{Version: 1, Roots: []cid.CID{first.CID}},
+				blocks: []Block{first, second},
+			},
+		},
+		{
+			name: "Given a CAR file without blocks, When it is read, Then it should return only the header",
+			in:   buildCAR(t, 1, nil),
+			out: out{
+				header: Header{Version: 1, Roots: []cid.CID{}},
+			},
+		},
+		{
+			name: "Given a block that does not match its CID, When it is read, Then it should return an error",
+			in:   buildCAR(t, 1, nil, Block{CID: first.CID, Data: []byte("tampered")}),
+			out: out{
+				header: Header{Version: 1, Roots: []cid.CID{}},
+				err:    "car: block does not match its cid " + first.CID.String(),
+			},
+		},
+		{
+			name: "Given a truncated block, When it is read, Then it should return an unexpected EOF error",
+			in:   buildCAR(t, 1, nil, first)[:len(buildCAR(t, 1, nil, first))-2],
+			out: out{
+				header: Header{Version: 1, Roots: []cid.CID{}},
+				err:    "car: fail to read block: unexpected EOF",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(bytes.NewReader(tt.in))
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.header, reader.Header())
+
+			var blocks []Block
+			for {
+				block, nextErr := reader.Next()
+				if errors.Is(nextErr, io.EOF) {
+					break
+				}
+				if nextErr != nil {
+					err = nextErr
+					break
+				}
+				blocks = append(blocks, block)
+			}
+
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+			} else {
+				assert.Equal(t, tt.out.blocks, blocks)
+			}
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		err  string
+	}{
+		{
+			name: "Given an empty input, When NewReader is called, Then it should return an unexpected EOF error",
+			in:   nil,
+			err:  "car: fail to read header: unexpected EOF",
+		},
+		{
+			name: "Given a CAR v2 header, When NewReader is called, Then it should return an unsupported version error",
+			in:   buildCAR(t, 2, nil),
+			err:  "car: unsupported version 2",
+		},
+		{
+			name: "Given a header larger than the maximum section size, When NewReader is called, Then it should return an error",
+			in:   binary.AppendUvarint(nil, MaxSectionSize+1),
+			err:  "car: fail to read header: section of 8388609 bytes is larger than the maximum of 8388608 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(bytes.NewReader(tt.in))
+
+			assert.Nil(t, reader)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	first, second := newBlock("first"), newBlock("second")
+
+	t.Run("Given a valid CAR file, When ReadAll is called, Then every block should be available by CID", func(t *testing.T) {
+		archive, err := ReadAll(bytes.NewReader(buildCAR(t, 1, []cid.CID{first.CID}, first, second)))
+
+		require.NoError(t, err)
+		assert.Equal(t, []cid.CID{first.CID}, archive.Roots)
+		assert.Equal(t, 2, archive.Len())
+		data, ok := archive.Get(second.CID)
+		assert.True(t, ok)
+		assert.Equal(t, second.Data, data)
+		_, ok = archive.Get(cid.Sum(cid.CodecRaw, nil))
+		assert.False(t, ok)
+	})
+
+	t.Run("Given an invalid CAR file, When ReadAll is called, Then it should return an error", func(t *testing.T) {
+		archive, err := ReadAll(bytes.NewReader(buildCAR(t, 1, nil, Block{CID: first.CID, Data: second.Data})))
+
+		assert.Nil(t, archive)
+		assert.Error(t, err)
+	})
+}
+
+func TestWriter(t *testing.T) {
+	first, second := newBlock("first"), newBlock("second")
+
+	t.Run("Given roots and blocks, When they are written, Then reading the CAR file should return them", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, first.CID)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(first))
+		require.NoError(t, writer.Write(second))
+
+		archive, err := ReadAll(&buf)
+
+		require.NoError(t, err)
+		assert.Equal(t, []cid.CID{first.CID}, archive.Roots)
+		assert.Equal(t, 2, archive.Len())
+		data, _ := archive.Get(first.CID)
+		assert.Equal(t, first.Data, data)
+	})
+}
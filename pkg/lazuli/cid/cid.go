@@ -0,0 +1,275 @@
+// Package cid implements the content identifiers (CIDs) used by the AT Protocol to link records, commits and blobs.
+//
+// Only CIDv1 is supported, which is the only version used by the AT Protocol.
+package cid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	CodecRaw     uint64 = 0x55
+	CodecDagCBOR uint64 = 0x71
+
+	HashSHA256 uint64 = 0x12
+)
+
+// cborTagLink is the CBOR tag used by DAG-CBOR to encode links.
+const cborTagLink = 42
+
+// DagCBOR encodes values as DAG-CBOR, writing map keys in the order it requires and CIDs as links. It is shared by
+// every encoding that is hashed or signed, such as CAR headers, commits and PLC operations, so they stay canonical.
+var DagCBOR, _ = cbor.EncOptions{Sort: cbor.SortLengthFirst}.EncMode()
+
+var base32Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// CID is a content identifier. It is comparable, so it can be used as a map key, and its zero value is an undefined
+// CID.
+type CID struct {
+	str string
+}
+
+// Decode reads the CID at the beginning of b, returning it and the number of bytes it takes.
+func Decode(b []byte) (CID, int, error) {
+	n := 0
+	next := func(field string) (uint64, error) {
+		v, size := binary.Uvarint(b[n:])
+		if size <= 0 {
+			return 0, fmt.Errorf("cid: invalid %s varint", field)
+		}
+		n += size
+		return v, nil
+	}
+
+	version, err := next("version")
+	if err != nil {
+		return CID{}, 0, err
+	}
+	if version != 1 {
+		return CID{}, 0, fmt.Errorf("cid: unsupported version %d", version)
+	}
+	if _, err := next("codec"); err != nil {
+		return CID{}, 0, err
+	}
+	if _, err := next("hash code"); err != nil {
+		return CID{}, 0, err
+	}
+	length, err := next("hash length")
+	if err != nil {
+		return CID{}, 0, err
+	}
+	if uint64(len(b)-n) < length {
+		return CID{}, 0, errors.New("cid: hash digest is too short")
+	}
+	n += int(length)
+
+	return CID{str: string(b[:n])}, n, nil
+}
+
+// Cast returns the CID encoded in b, which must not have any trailing bytes.
+func Cast(b []byte) (CID, error) {
+	c, n, err := Decode(b)
+	if err != nil {
+		return CID{}, err
+	}
+	if n != len(b) {
+		return CID{}, errors.New("cid: trailing bytes after cid")
+	}
+	return c, nil
+}
+
+// Parse parses the string form of a CID, which is the base32 multibase encoding of its binary form.
+func Parse(s string) (CID, error) {
+	if s == "" {
+		return CID{}, errors.New("cid: empty string")
+	}
+	if s[0] != 'b' {
+		return CID{}, fmt.Errorf("cid: unsupported multibase prefix %q", s[0])
+	}
+
+	b, err := base32Encoding.DecodeString(strings.ToUpper(s[1:]))
+	if err != nil {
+		return CID{}, fmt.Errorf("cid: %w", err)
+	}
+	return Cast(b)
+}
+
+// Sum returns the CIDv1 of data encoded with the given codec, using a SHA-256 hash.
+func Sum(codec uint64, data []byte) CID {
+	digest := sha256.Sum256(data)
+
+	b := binary.AppendUvarint(nil, 1)
+	b = binary.AppendUvarint(b, codec)
+	b = binary.AppendUvarint(b, HashSHA256)
+	b = binary.AppendUvarint(b, uint64(len(digest)))
+	b = append(b, digest[:]...)
+
+	return CID{str: string(b)}
+}
+
+// Defined reports whether the CID holds a value.
+func (c CID) Defined() bool {
+	return c.str != ""
+}
+
+// Bytes returns the binary form of the CID.
+func (c CID) Bytes() []byte {
+	return []byte(c.str)
+}
+
+func (c CID) prefix() (version, codec uint64, hashOffset int) {
+	b := []byte(c.str)
+	version, n := binary.Uvarint(b)
+	codec, m := binary.Uvarint(b[n:])
+	return version, codec, n + m
+}
+
+// Version returns the CID version, which is always 1 for defined CIDs.
+func (c CID) Version() uint64 {
+	if !c.Defined() {
+		return 0
+	}
+	version, _, _ := c.prefix()
+	return version
+}
+
+// Codec returns the multicodec of the content, such as CodecDagCBOR for records and commits.
+func (c CID) Codec() uint64 {
+	if !c.Defined() {
+		return 0
+	}
+	_, codec, _ := c.prefix()
+	return codec
+}
+
+// Hash returns the multihash of the content, made of the hash function code, the digest length and the digest.
+func (c CID) Hash() []byte {
+	if !c.Defined() {
+		return nil
+	}
+	_, _, offset := c.prefix()
+	return []byte(c.str[offset:])
+}
+
+// HashCode returns the multicodec of the hash function, such as HashSHA256.
+func (c CID) HashCode() uint64 {
+	code, _ := binary.Uvarint(c.Hash())
+	return code
+}
+
+// Digest returns the hash digest of the content.
+func (c CID) Digest() []byte {
+	hash := c.Hash()
+	_, n := binary.Uvarint(hash)
+	_, m := binary.Uvarint(hash[n:])
+	return hash[n+m:]
+}
+
+// Matches reports whether data is the content identified by the CID. It is only able to check SHA-256 hashes, and
+// returns false for any other hash function.
+func (c CID) Matches(data []byte) bool {
+	if c.HashCode() != HashSHA256 {
+		return false
+	}
+	digest := sha256.Sum256(data)
+	return bytes.Equal(c.Digest(), digest[:])
+}
+
+// String returns the CID in its base32 multibase form, such as "bafyrei...", or an empty string for an undefined
+// CID.
+func (c CID) String() string {
+	if !c.Defined() {
+		return ""
+	}
+	return "b" + strings.ToLower(base32Encoding.EncodeToString([]byte(c.str)))
+}
+
+// MarshalCBOR encodes the CID as a DAG-CBOR link, or as null when it is undefined.
+func (c CID) MarshalCBOR() ([]byte, error) {
+	if !c.Defined() {
+		return []byte{0xf6}, nil
+	}
+	return DagCBOR.Marshal(cbor.Tag{Number: cborTagLink, Content: append([]byte{0x00}, c.Bytes()...)})
+}
+
+// UnmarshalCBOR decodes a DAG-CBOR link, which is a CBOR tag 42 wrapping the binary CID prefixed with a zero byte.
+func (c *CID) UnmarshalCBOR(data []byte) error {
+	if len(data) == 1 && (data[0] == 0xf6 || data[0] == 0xf7) {
+		*c = CID{}
+		return nil
+	}
+
+	var tag cbor.RawTag
+	if err := cbor.Unmarshal(data, &tag); err != nil {
+		return fmt.Errorf("cid: %w", err)
+	}
+	if tag.Number != cborTagLink {
+		return fmt.Errorf("cid: unexpected cbor tag %d", tag.Number)
+	}
+
+	var b []byte
+	if err := cbor.Unmarshal(tag.Content, &b); err != nil {
+		return fmt.Errorf("cid: %w", err)
+	}
+
+	parsed, err := FromLinkBytes(b)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// FromLinkBytes returns the CID of the content of a DAG-CBOR link, which is the binary CID prefixed with a zero byte.
+func FromLinkBytes(b []byte) (CID, error) {
+	if len(b) == 0 || b[0] != 0 {
+		return CID{}, errors.New("cid: link must start with the identity multibase prefix")
+	}
+	return Cast(b[1:])
+}
+
+// MarshalJSON encodes the CID as its string form, or as null when it is undefined.
+func (c CID) MarshalJSON() ([]byte, error) {
+	if !c.Defined() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON decodes a CID from its string form, or from the {"$link": "..."} object used by the JSON form of the
+// AT Protocol data model.
+func (c *CID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = CID{}
+		return nil
+	}
+
+	var s string
+	if len(data) > 0 && data[0] == '{' {
+		var link struct {
+			Link string `json:"$link"`
+		}
+		if err := json.Unmarshal(data, &link); err != nil {
+			return fmt.Errorf("cid: %w", err)
+		}
+		s = link.Link
+	} else if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("cid: %w", err)
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
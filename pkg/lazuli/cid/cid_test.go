@@ -0,0 +1,324 @@
+package cid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSum(t *testing.T) {
+	type in struct {
+		codec uint64
+		data  []byte
+	}
+
+	type out struct {
+		str string
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given empty raw data, When Sum is called, Then it should return the well known empty raw CID",
+			in:   in{codec: CodecRaw, data: []byte{}},
+			out:  out{str: "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"},
+		},
+		{
+			name: "Given DAG-CBOR data, When Sum is called, Then it should return a dag-cbor CID",
+			in:   in{codec: CodecDagCBOR, data: []byte{0xa0}},
+			out:  out{str: "bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Sum(tt.in.codec, tt.in.data)
+
+			assert.Equal(t, tt.out.str, c.String())
+			assert.Equal(t, uint64(1), c.Version())
+			assert.Equal(t, tt.in.codec, c.Codec())
+			assert.Equal(t, HashSHA256, c.HashCode())
+			assert.Len(t, c.Digest(), 32)
+			assert.True(t, c.Matches(tt.in.data))
+			assert.False(t, c.Matches([]byte("other data")))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	valid := Sum(CodecDagCBOR, []byte("test")).Bytes()
+
+	type out struct {
+		cid CID
+		n   int
+		err string
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		out  out
+	}{
+		{
+			name: "Given a CID followed by data, When Decode is called, Then it should return the CID and its length",
+			in:   append(append([]byte{}, valid...), "block data"...),
+			out:  out{cid: Sum(CodecDagCBOR, []byte("test")), n: len(valid)},
+		},
+		{
+			name: "Given a CIDv0, When Decode is called, Then it should return an unsupported version error",
+			in:   append([]byte{0x12, 0x20}, make([]byte, 32)...),
+			out:  out{err: "cid: unsupported version 18"},
+		},
+		{
+			name: "Given a truncated CID, When Decode is called, Then it should return an error",
+			in:   valid[:10],
+			out:  out{err: "cid: hash digest is too short"},
+		},
+		{
+			name: "Given no data, When Decode is called, Then it should return an error",
+			in:   nil,
+			out:  out{err: "cid: invalid version varint"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, n, err := Decode(tt.in)
+
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+				assert.False(t, c.Defined())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.cid, c)
+				assert.Equal(t, tt.out.n, n)
+			}
+		})
+	}
+}
+
+func TestCast(t *testing.T) {
+	t.Run("Given a CID with trailing bytes, When Cast is called, Then it should return an error", func(t *testing.T) {
+		_, err := Cast(append(Sum(CodecRaw, nil).Bytes(), 0x00))
+
+		assert.EqualError(t, err, "cid: trailing bytes after cid")
+	})
+}
+
+func TestCID_UnmarshalCBOR(t *testing.T) {
+	c := Sum(CodecDagCBOR, []byte("test"))
+	link := func(t *testing.T, number uint64, content []byte) []byte {
+		b, err := cbor.Marshal(cbor.Tag{Number: number, Content: content})
+		require.NoError(t, err)
+		return b
+	}
+
+	type out struct {
+		cid CID
+		err string
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		out  out
+	}{
+		{
+			name: "Given a DAG-CBOR link, When it is decoded, Then it should return the CID",
+			in:   link(t, 42, append([]byte{0x00}, c.Bytes()...)),
+			out:  out{cid: c},
+		},
+		{
+			name: "Given a null, When it is decoded, Then it should return an undefined CID",
+			in:   []byte{0xf6},
+			out:  out{},
+		},
+		{
+			name: "Given another tag, When it is decoded, Then it should return an error",
+			in:   link(t, 43, append([]byte{0x00}, c.Bytes()...)),
+			out:  out{err: "cid: unexpected cbor tag 43"},
+		},
+		{
+			name: "Given a link without the multibase prefix, When it is decoded, Then it should return an error",
+			in:   link(t, 42, c.Bytes()),
+			out:  out{err: "cid: link must start with the identity multibase prefix"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got CID
+			err := cbor.Unmarshal(tt.in, &got)
+
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.cid, got)
+			}
+		})
+	}
+}
+
+func TestCID_undefined(t *testing.T) {
+	t.Run("Given an undefined CID, When its accessors are called, Then they should return zero values", func(t *testing.T) {
+		var c CID
+
+		assert.False(t, c.Defined())
+		assert.Equal(t, "", c.String())
+		assert.Equal(t, uint64(0), c.Version())
+		assert.Equal(t, uint64(0), c.Codec())
+		assert.Nil(t, c.Hash())
+		assert.False(t, c.Matches(nil))
+	})
+}
+
+func TestParse(t *testing.T) {
+	c := Sum(CodecDagCBOR, []byte("test"))
+
+	type out struct {
+		cid CID
+		err string
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given the string form of a CID, When Parse is called, Then it should return the CID",
+			in:   c.String(),
+			out:  out{cid: c},
+		},
+		{
+			name: "Given an empty string, When Parse is called, Then it should return an error",
+			in:   "",
+			out:  out{err: "cid: empty string"},
+		},
+		{
+			name: "Given a base58 CIDv0, When Parse is called, Then it should return an unsupported multibase error",
+			in:   "QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n",
+			out:  out{err: `cid: unsupported multibase prefix 'Q'`},
+		},
+		{
+			name: "Given an invalid base32 string, When Parse is called, Then it should return an error",
+			in:   "b!!!",
+			out:  out{err: "cid: illegal base32 data at input byte 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.cid, got)
+			}
+		})
+	}
+}
+
+func TestCID_JSON(t *testing.T) {
+	c := Sum(CodecDagCBOR, []byte("test"))
+
+	type out struct {
+		cid CID
+		err bool
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a CID string, When it is decoded from JSON, Then it should return the CID",
+			in:   `"` + c.String() + `"`,
+			out:  out{cid: c},
+		},
+		{
+			name: "Given a $link object, When it is decoded from JSON, Then it should return the CID",
+			in:   `{"$link":"` + c.String() + `"}`,
+			out:  out{cid: c},
+		},
+		{
+			name: "Given null, When it is decoded from JSON, Then it should return an undefined CID",
+			in:   `null`,
+			out:  out{},
+		},
+		{
+			name: "Given an invalid CID string, When it is decoded from JSON, Then it should return an error",
+			in:   `"not-a-cid"`,
+			out:  out{err: true},
+		},
+		{
+			name: "Given a number, When it is decoded from JSON, Then it should return an error",
+			in:   `42`,
+			out:  out{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got CID
+			err := json.Unmarshal([]byte(tt.in), &got)
+
+			if tt.out.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.cid, got)
+			}
+		})
+	}
+
+	t.Run("Given a CID, When it is encoded to JSON, Then it should be its string form", func(t *testing.T) {
+		b, err := json.Marshal(struct {
+			Defined   CID `json:"defined"`
+			Undefined CID `json:"undefined"`
+		}{Defined: c})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"defined":"`+c.String()+`","undefined":null}`, string(b))
+	})
+}
+
+func TestCID_MarshalCBOR(t *testing.T) {
+	t.Run("Given a CID, When it is encoded to CBOR and decoded back, Then it should be the same CID", func(t *testing.T) {
+		c := Sum(CodecDagCBOR, []byte("test"))
+
+		b, err := cbor.Marshal(c)
+		require.NoError(t, err)
+		var got CID
+		require.NoError(t, cbor.Unmarshal(b, &got))
+
+		assert.Equal(t, c, got)
+		assert.Equal(t, []byte{0xd8, 0x2a}, b[:2])
+	})
+
+	t.Run("Given an undefined CID, When it is encoded to CBOR, Then it should be null", func(t *testing.T) {
+		b, err := cbor.Marshal(CID{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xf6}, b)
+	})
+}
+
+func TestDagCBOR(t *testing.T) {
+	t.Run("Given a map, When it is encoded, Then its keys should be sorted shortest first", func(t *testing.T) {
+		b, err := DagCBOR.Marshal(map[string]int{"bb": 1, "c": 2, "a": 3})
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xa3, 0x61, 'a', 0x03, 0x61, 'c', 0x02, 0x62, 'b', 'b', 0x01}, b)
+	})
+}
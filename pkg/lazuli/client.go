@@ -1,12 +1,14 @@
 package lazuli
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
@@ -14,84 +16,146 @@ import (
 )
 
 type Client interface {
-	ConsumeFirehose(ctx context.Context, handler HandlerCommitFn) error
-	CreateSession(ctx context.Context, identifier, password string) (*bsky.AuthResponse, error)
-	CreatePostRecord(ctx context.Context, p bsky.CreateRecordParams) error
-	CreateRepostRecord(ctx context.Context, p bsky.CreateRecordParams) error
-	CreateLikeRecord(ctx context.Context, p bsky.CreateRecordParams) error
+	ConsumeFirehose(ctx context.Context, handler HandlerCommitFn, opts ...FirehoseOption) error
+	CreateSession(ctx context.Context, identifier, password string, opts ...CreateSessionOption) (*bsky.AuthResponse, error)
+	CreateRecord(ctx context.Context, collection string, record any, opts ...WriteRecordOption) (*bsky.CreateRecordResponse, error)
+	CreatePostRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error)
+	CreateRepostRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error)
+	CreateLikeRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error)
 	GetPosts(ctx context.Context, atURIs ...string) (bsky.Posts, error)
 	GetPost(ctx context.Context, atURI string) (*bsky.Post, error)
+	ResumeSession(ctx context.Context, session *bsky.AuthResponse) (*bsky.AuthResponse, error)
+	GetSession(ctx context.Context) (*bsky.SessionResponse, error)
+	RefreshSession(ctx context.Context) (*bsky.AuthResponse, error)
+	DeleteSession(ctx context.Context) error
+	Session() *bsky.AuthResponse
+	LoadSession(ctx context.Context) (*bsky.AuthResponse, error)
+	GetRepo(ctx context.Context, did, since string) (io.ReadCloser, error)
+	ForEachRecord(ctx context.Context, did string, fn func(record bsky.RepoRecord) error, collections ...string) error
+	ListRepos(ctx context.Context, cursor string, limit int) (*bsky.ListReposResponse, error)
+	GetRecord(ctx context.Context, repo, collection, rkey string) (*bsky.RecordValue, error)
+	ListRecords(ctx context.Context, repo, collection, cursor string, limit int) (*bsky.ListRecordsResponse, error)
+	PutRecord(ctx context.Context, collection, rkey string, record any, opts ...WriteRecordOption) (*bsky.PutRecordResponse, error)
+	DeleteRecord(ctx context.Context, collection, rkey string, opts ...WriteRecordOption) (*bsky.DeleteRecordResponse, error)
+	ApplyWrites(ctx context.Context, writes []bsky.WriteOp, opts ...WriteRecordOption) (*bsky.ApplyWritesResponse, error)
 }
 
 type client struct {
 	xrpcURL    string
 	wsURL      string
 	wsDialer   *websocket.Dialer
+	sessionMu  sync.RWMutex
 	session    *bsky.AuthResponse
+	loadErr    error
+	refreshMu  sync.Mutex
+	refreshing *sessionRefresh
+	store      SessionStore
+	identities IdentityResolver
 	httpClient *http.Client
+	timeout    time.Duration
+	userAgent  string
+	headers    http.Header
 }
 
-func NewClient(xrpcURL, wsURL string) Client {
-	dialer := *websocket.DefaultDialer
-	// TODO: improve to use a more appropriate http client config
-	return &client{
-		xrpcURL:    xrpcURL,
-		wsURL:      wsURL,
-		wsDialer:   &dialer,
-		httpClient: http.DefaultClient,
+const (
+	defaultTimeout   = 30 * time.Second
+	defaultUserAgent = "go-lazuli"
+)
+
+func NewClient(xrpcURL, wsURL string, opts ...Option) Client {
+	c := &client{
+		xrpcURL:   xrpcURL,
+		wsURL:     wsURL,
+		userAgent: defaultUserAgent,
+		headers:   http.Header{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if c.wsDialer == nil {
+		dialer := *websocket.DefaultDialer
+		c.wsDialer = &dialer
 	}
+	if c.timeout > 0 {
+		// copies are changed so the client and dialer given through options are left untouched
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+
+		dialer := *c.wsDialer
+		dialer.HandshakeTimeout = c.timeout
+		c.wsDialer = &dialer
+	}
+
+	if c.store != nil {
+		// a store that cannot be read is reported by LoadSession and by the first call needing a session, instead of
+		// being taken for an empty store
+		session, err := c.store.Load(context.Background())
+		switch {
+		case err == nil:
+			c.session = session
+		case !errors.Is(err, ErrSessionNotFound):
+			c.loadErr = err
+		}
+	}
+
+	return c
 }
 
-func (c *client) createRecord(ctx context.Context, p bsky.CreateRecordParams) error {
-	body := bsky.RequestRecordBody{
-		LexiconTypeID: p.Resource,
-		Collection:    p.Resource,
-		Repo:          c.session.DID,
-		Record: bsky.RequestRecord{
-			Text: p.Text,
-			Subject: bsky.RepoStrongRef{
-				URI: p.URI,
-				CID: p.CID,
-			},
-			CreatedAt: time.Now().UTC(),
-		},
-	}
-
-	jsonBody, _ := json.Marshal(body)
-
-	reqURL := fmt.Sprintf("%s/com.atproto.repo.createRecord", c.xrpcURL)
-	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(jsonBody))
+// newRequest creates a request with the user agent and the extra headers configured for the client.
+func (c *client) newRequest(ctx context.Context, method, reqURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
 	if err != nil {
-		return newError(http.StatusInternalServerError, "fail to create record request struct", err.Error())
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.session.AccessJwt))
-	req.Header.Set("Content-Type", "application/json")
+	for key, values := range c.requestHeader() {
+		req.Header[key] = values
+	}
 
-	resp, doErr := c.httpClient.Do(req)
-	if doErr != nil {
-		return newError(http.StatusInternalServerError, "fail to do request to create record", doErr.Error())
+	return req, nil
+}
+
+// requestHeader returns the headers sent with every HTTP request and websocket handshake.
+func (c *client) requestHeader() http.Header {
+	header := c.headers.Clone()
+	if header == nil {
+		header = http.Header{}
 	}
-	if resp.StatusCode != http.StatusOK {
-		return newErrorFromResponse(resp, "create record request failed")
+	if c.userAgent != "" && header.Get("User-Agent") == "" {
+		header.Set("User-Agent", c.userAgent)
 	}
-
-	return nil
+	return header
 }
 
-func (c *client) CreatePostRecord(ctx context.Context, p bsky.CreateRecordParams) error {
-	p.Resource = "app.bsky.feed.post"
-	return c.createRecord(ctx, p)
+// CreatePostRecord creates a post with the text of p. Replies, embeds and facets are created with CreateRecord and a
+// bsky.PostRecord.
+func (c *client) CreatePostRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error) {
+	return c.CreateRecord(ctx, bsky.CollectionPost, bsky.PostRecord{
+		Text:      p.Text,
+		CreatedAt: time.Now().UTC(),
+	})
 }
 
-func (c *client) CreateRepostRecord(ctx context.Context, p bsky.CreateRecordParams) error {
-	p.Resource = "app.bsky.feed.repost"
-	return c.createRecord(ctx, p)
+// CreateRepostRecord reposts the post identified by the URI and CID of p.
+func (c *client) CreateRepostRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error) {
+	return c.CreateRecord(ctx, bsky.CollectionRepost, bsky.RepostRecord{
+		Subject:   bsky.RepoStrongRef{URI: p.URI, CID: p.CID},
+		CreatedAt: time.Now().UTC(),
+	})
 }
 
-func (c *client) CreateLikeRecord(ctx context.Context, p bsky.CreateRecordParams) error {
-	p.Resource = "app.bsky.feed.like"
-	return c.createRecord(ctx, p)
+// CreateLikeRecord likes the post identified by the URI and CID of p.
+func (c *client) CreateLikeRecord(ctx context.Context, p bsky.CreateRecordParams) (*bsky.CreateRecordResponse, error) {
+	return c.CreateRecord(ctx, bsky.CollectionLike, bsky.LikeRecord{
+		Subject:   bsky.RepoStrongRef{URI: p.URI, CID: p.CID},
+		CreatedAt: time.Now().UTC(),
+	})
 }
 
 func (c *client) GetPosts(ctx context.Context, atURIs ...string) (bsky.Posts, error) {
@@ -105,27 +169,35 @@ func (c *client) GetPosts(ctx context.Context, atURIs ...string) (bsky.Posts, er
 	query := url.Values{
 		"uris": atURIs,
 	}
-	query.Encode()
-	reqURL := fmt.Sprintf("%s/app.bsky.feed.getPosts?%s", c.xrpcURL, query.Encode())
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, newError(http.StatusInternalServerError, "fail to create get posts request struct", err.Error())
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.session.AccessJwt))
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, doErr := c.httpClient.Do(req)
-	if doErr != nil {
-		return nil, newError(http.StatusInternalServerError, "fail to do request to get posts", doErr.Error())
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, newErrorFromResponse(resp, "get posts request failed")
-	}
-
 	var postsResponse bsky.PostResponse
-	if decodeErr := json.NewDecoder(resp.Body).Decode(&postsResponse); decodeErr != nil {
-		return nil, newError(http.StatusInternalServerError, "fail to decode get posts response", decodeErr.Error())
+	err := c.withSessionRefresh(ctx, func(sess *bsky.AuthResponse) error {
+		reqURL := fmt.Sprintf("%s/app.bsky.feed.getPosts?%s", c.serviceURL(sess), query.Encode())
+		req, err := c.newRequest(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return newError(http.StatusInternalServerError, "fail to create get posts request struct", err.Error())
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sess.AccessJwt))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, doErr := c.httpClient.Do(req)
+		if doErr != nil {
+			return newError(http.StatusInternalServerError, "fail to do request to get posts", doErr.Error())
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return newErrorFromResponse(resp, "get posts request failed")
+		}
+
+		if decodeErr := json.NewDecoder(resp.Body).Decode(&postsResponse); decodeErr != nil {
+			return newError(http.StatusInternalServerError, "fail to decode get posts response", decodeErr.Error())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return postsResponse.Posts, nil
@@ -3,14 +3,26 @@ package lazuli
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var testRecordResponse = bsky.CreateRecordResponse{
+	URI:              "at://test-did/app.bsky.feed.post/3l3qo2vutsw2b",
+	CID:              cid.Sum(cid.CodecDagCBOR, []byte("record")),
+	Commit:           &bsky.CommitMeta{CID: cid.Sum(cid.CodecDagCBOR, []byte("commit")), Rev: "3l3qo2vuowo2b"},
+	ValidationStatus: bsky.ValidationStatusValid,
+}
+
 func TestClient_CreatePostRecord(t *testing.T) {
 	type in struct {
 		ctx    context.Context
@@ -18,7 +30,8 @@ func TestClient_CreatePostRecord(t *testing.T) {
 	}
 
 	type out struct {
-		err error
+		response *bsky.CreateRecordResponse
+		err      error
 	}
 
 	tests := []struct {
@@ -39,10 +52,35 @@ func TestClient_CreatePostRecord(t *testing.T) {
 				},
 			},
 			out: out{
-				err: nil,
+				response: &testRecordResponse,
 			},
 			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
+			},
+		},
+		{
+			name: "Given a CreatePostRecord function call, When the access token has expired, Then it should refresh the session and retry",
+			in: in{
+				ctx: context.Background(),
+				params: bsky.CreateRecordParams{
+					Text:     "test text",
+					Resource: "app.bsky.feed.post",
+				},
+			},
+			out: out{
+				response: &testRecordResponse,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/com.atproto.server.refreshSession" {
+					_ = json.NewEncoder(w).Encode(bsky.AuthResponse{AccessJwt: "new-token", DID: "test-did"})
+					return
+				}
+				if r.Header.Get("Authorization") != "Bearer new-token" {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has expired"})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
 			},
 		},
 		{
@@ -64,6 +102,22 @@ func TestClient_CreatePostRecord(t *testing.T) {
 				_ = json.NewEncoder(w).Encode(map[string]string{"message": "request failed"})
 			},
 		},
+		{
+			name: "Given a CreatePostRecord function call, When the response is not valid JSON, Then it should return an error",
+			in: in{
+				ctx: context.Background(),
+				params: bsky.CreateRecordParams{
+					Text:     "test text",
+					Resource: "app.bsky.feed.post",
+				},
+			},
+			out: out{
+				err: newError(http.StatusInternalServerError, "fail to decode create record response", "unexpected EOF"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"uri":`))
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -77,13 +131,15 @@ func TestClient_CreatePostRecord(t *testing.T) {
 				httpClient: server.Client(),
 			}
 
-			err := lazuliClient.CreatePostRecord(tt.in.ctx, tt.in.params)
+			response, err := lazuliClient.CreatePostRecord(tt.in.ctx, tt.in.params)
 
 			if tt.out.err != nil {
 				assert.Error(t, err)
 				assert.Equal(t, tt.out.err, err)
+				assert.Nil(t, response)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.out.response, response)
 			}
 		})
 	}
@@ -130,6 +186,23 @@ func TestClient_GetPosts(t *testing.T) {
 				_ = json.NewEncoder(w).Encode(posts)
 			},
 		},
+		{
+			name: "Given a GetPosts function call, When the posts have CIDs, Then it should decode them as CIDs",
+			in: in{
+				ctx:    context.Background(),
+				atURIs: []string{"test-uri-1"},
+			},
+			out: out{
+				posts: bsky.Posts{
+					{URI: "test-uri-1", CID: cid.Sum(cid.CodecDagCBOR, []byte("post"))},
+				},
+				err: nil,
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = fmt.Fprintf(w, `{"posts":[{"uri":"test-uri-1","cid":%q,"indexedAt":"0001-01-01T00:00:00Z","record":{"createdAt":"0001-01-01T00:00:00Z"},"author":{"createdAt":"0001-01-01T00:00:00Z"}}]}`, cid.Sum(cid.CodecDagCBOR, []byte("post")).String())
+			},
+		},
 		{
 			name: "Given a GetPosts function call, When there is a request failure, Then it should return an error",
 			in: in{
@@ -272,7 +345,8 @@ func TestClient_CreateRepostRecord(t *testing.T) {
 		params bsky.CreateRecordParams
 	}
 	type out struct {
-		err error
+		response *bsky.CreateRecordResponse
+		err      error
 	}
 	tests := []struct {
 		name    string
@@ -292,10 +366,10 @@ func TestClient_CreateRepostRecord(t *testing.T) {
 				},
 			},
 			out: out{
-				err: nil,
+				response: &testRecordResponse,
 			},
 			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
 			},
 		},
 		{
@@ -327,12 +401,14 @@ func TestClient_CreateRepostRecord(t *testing.T) {
 				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
 				httpClient: server.Client(),
 			}
-			err := lazuliClient.CreateRepostRecord(tt.in.ctx, tt.in.params)
+			response, err := lazuliClient.CreateRepostRecord(tt.in.ctx, tt.in.params)
 			if tt.out.err != nil {
 				assert.Error(t, err)
 				assert.Equal(t, tt.out.err, err)
+				assert.Nil(t, response)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.out.response, response)
 			}
 		})
 	}
@@ -344,7 +420,8 @@ func TestClient_CreateLikeRecord(t *testing.T) {
 		params bsky.CreateRecordParams
 	}
 	type out struct {
-		err error
+		response *bsky.CreateRecordResponse
+		err      error
 	}
 	tests := []struct {
 		name    string
@@ -364,10 +441,10 @@ func TestClient_CreateLikeRecord(t *testing.T) {
 				},
 			},
 			out: out{
-				err: nil,
+				response: &testRecordResponse,
 			},
 			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
 			},
 		},
 		{
@@ -399,13 +476,116 @@ func TestClient_CreateLikeRecord(t *testing.T) {
 				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
 				httpClient: server.Client(),
 			}
-			err := lazuliClient.CreateLikeRecord(tt.in.ctx, tt.in.params)
+			response, err := lazuliClient.CreateLikeRecord(tt.in.ctx, tt.in.params)
 			if tt.out.err != nil {
 				assert.Error(t, err)
 				assert.Equal(t, tt.out.err, err)
+				assert.Nil(t, response)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.out.response, response)
 			}
 		})
 	}
 }
+
+func TestNewClient(t *testing.T) {
+	t.Run("Given a session store with a saved session, When NewClient is called, Then it should resume the session", func(t *testing.T) {
+		store := NewMemorySessionStore()
+		saved := &bsky.AuthResponse{DID: "test-did", AccessJwt: "access"}
+		_ = store.Save(context.Background(), saved)
+
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(store))
+
+		assert.Equal(t, saved, lazuliClient.Session())
+	})
+
+	t.Run("Given an empty session store, When NewClient is called, Then it should have no session", func(t *testing.T) {
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(NewMemorySessionStore()))
+
+		assert.Nil(t, lazuliClient.Session())
+	})
+
+	t.Run("Given an unreadable session store, When NewClient is called, Then the calls needing a session should return the load error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(NewFileSessionStore(path)))
+
+		assert.Nil(t, lazuliClient.Session())
+		_, err := lazuliClient.GetSession(context.Background())
+		assert.Equal(t, http.StatusInternalServerError, err.(*Error).Code)
+		assert.Equal(t, "fail to load stored session", err.(*Error).Message)
+		assert.Equal(t, err, lazuliClient.DeleteSession(context.Background()))
+	})
+}
+
+func TestClient_LoadSession(t *testing.T) {
+	t.Run("Given a session store with a saved session, When LoadSession is called, Then it should use and return the session", func(t *testing.T) {
+		store := NewMemorySessionStore()
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(store))
+		saved := &bsky.AuthResponse{DID: "test-did", AccessJwt: "access"}
+		_ = store.Save(context.Background(), saved)
+
+		session, err := lazuliClient.LoadSession(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, saved, session)
+		assert.Equal(t, saved, lazuliClient.Session())
+	})
+
+	t.Run("Given an empty session store, When LoadSession is called, Then it should return no session", func(t *testing.T) {
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(NewMemorySessionStore()))
+
+		session, err := lazuliClient.LoadSession(context.Background())
+
+		assert.NoError(t, err)
+		assert.Nil(t, session)
+	})
+
+	t.Run("Given no session store, When LoadSession is called, Then it should return no session", func(t *testing.T) {
+		session, err := NewClient("http://localhost", "ws://localhost").LoadSession(context.Background())
+
+		assert.NoError(t, err)
+		assert.Nil(t, session)
+	})
+
+	t.Run("Given a corrupted session file, When LoadSession is called, Then it should return an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		lazuliClient := NewClient("http://localhost", "ws://localhost", WithSessionStore(NewFileSessionStore(path)))
+
+		session, err := lazuliClient.LoadSession(context.Background())
+
+		assert.Nil(t, session)
+		assert.Equal(t, "fail to load stored session", err.(*Error).Message)
+	})
+}
+
+func TestClient_GetPosts_contextCancellation(t *testing.T) {
+	t.Run("Given a canceled context, When GetPosts is called, Then it should not send the request", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			session:    &bsky.AuthResponse{AccessJwt: "test-token"},
+			httpClient: server.Client(),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		posts, err := lazuliClient.GetPosts(ctx, "test-uri")
+
+		assert.Nil(t, posts)
+		var lazuliErr *Error
+		assert.ErrorAs(t, err, &lazuliErr)
+		assert.Equal(t, "fail to do request to get posts", lazuliErr.Message)
+		assert.Contains(t, lazuliErr.Details, context.Canceled.Error())
+		assert.False(t, called)
+	})
+}
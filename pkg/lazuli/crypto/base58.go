@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"errors"
+	"math/big"
+)
+
+// base58Alphabet is the bitcoin alphabet used by the base58btc multibase encoding.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Radix = big.NewInt(58)
+
+func base58Encode(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+
+	n := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+	out := make([]byte, 0, len(b)*138/100+1)
+	for n.Sign() > 0 {
+		n.DivMod(n, base58Radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	n := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		digit := indexBase58(s[i])
+		if digit < 0 {
+			return nil, errors.New("crypto: invalid base58 character")
+		}
+		n.Mul(n, base58Radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+func indexBase58(c byte) int {
+	for i := 0; i < len(base58Alphabet); i++ {
+		if base58Alphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,134 @@
+// Package crypto parses and verifies the signing keys used by the AT Protocol, which are secp256k1 (K-256) and NIST
+// P-256 keys, encoded as multibase strings in DID documents or as did:key identifiers.
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when a signature does not match the content and the key.
+var ErrInvalidSignature = errors.New("crypto: invalid signature")
+
+// Curve is an elliptic curve supported by the AT Protocol.
+type Curve string
+
+const (
+	CurveK256 Curve = "secp256k1"
+	CurveP256 Curve = "p256"
+)
+
+// multicodec prefixes identifying the curve of compressed public keys.
+const (
+	multicodecK256Pub uint64 = 0xe7
+	multicodecP256Pub uint64 = 0x1200
+)
+
+// didKeyPrefix is the prefix of did:key identifiers.
+const didKeyPrefix = "did:key:"
+
+// signatureSize is the size of the compact signatures used by the AT Protocol, which are the 32-byte r and s
+// values of an ECDSA signature concatenated.
+const signatureSize = 64
+
+// PublicKey is a public key able to verify signatures made by the matching private key.
+type PublicKey interface {
+	// Curve returns the elliptic curve of the key.
+	Curve() Curve
+	// Bytes returns the compressed form of the key.
+	Bytes() []byte
+	// Verify checks sig against the SHA-256 hash of content. Only low-S signatures are accepted, as required by the
+	// AT Protocol.
+	Verify(content, sig []byte) error
+	// Multibase returns the key with its multicodec prefix, encoded as a base58btc multibase string.
+	Multibase() string
+	// DIDKey returns the did:key identifier of the key.
+	DIDKey() string
+}
+
+// PrivateKey is a private key able to sign content.
+type PrivateKey interface {
+	// PublicKey returns the public key of the private key.
+	PublicKey() PublicKey
+	// Sign signs the SHA-256 hash of content, returning a low-S compact signature.
+	Sign(content []byte) ([]byte, error)
+}
+
+// GeneratePrivateKey creates a random private key for the given curve.
+func GeneratePrivateKey(curve Curve) (PrivateKey, error) {
+	switch curve {
+	case CurveK256:
+		return generatePrivateKeyK256()
+	case CurveP256:
+		return generatePrivateKeyP256()
+	default:
+		return nil, fmt.Errorf("crypto: unsupported curve %q", curve)
+	}
+}
+
+// ParsePublicKey parses a compressed public key for the given curve.
+func ParsePublicKey(curve Curve, b []byte) (PublicKey, error) {
+	switch curve {
+	case CurveK256:
+		return parsePublicKeyK256(b)
+	case CurveP256:
+		return parsePublicKeyP256(b)
+	default:
+		return nil, fmt.Errorf("crypto: unsupported curve %q", curve)
+	}
+}
+
+// ParsePublicMultibase parses a public key encoded as a base58btc multibase string holding the compressed key
+// prefixed with its multicodec, which is the form used by "Multikey" verification methods.
+func ParsePublicMultibase(s string) (PublicKey, error) {
+	b, err := decodeMultibase(s)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, errors.New("crypto: invalid multicodec prefix")
+	}
+
+	switch codec {
+	case multicodecK256Pub:
+		return parsePublicKeyK256(b[n:])
+	case multicodecP256Pub:
+		return parsePublicKeyP256(b[n:])
+	default:
+		return nil, fmt.Errorf("crypto: unsupported key multicodec 0x%x", codec)
+	}
+}
+
+// ParsePublicLegacyMultibase parses a public key encoded as a base58btc multibase string holding the bare key, with
+// no multicodec prefix, which is the form used by the legacy EcdsaSecp256k1VerificationKey2019 and
+// EcdsaSecp256r1VerificationKey2019 verification methods. The key may be compressed or uncompressed.
+func ParsePublicLegacyMultibase(curve Curve, s string) (PublicKey, error) {
+	b, err := decodeMultibase(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(curve, b)
+}
+
+// ParsePublicDIDKey parses a did:key identifier, such as the rotation keys of did:plc documents.
+func ParsePublicDIDKey(did string) (PublicKey, error) {
+	if !strings.HasPrefix(did, didKeyPrefix) {
+		return nil, fmt.Errorf("crypto: %q is not a did:key", did)
+	}
+	return ParsePublicMultibase(strings.TrimPrefix(did, didKeyPrefix))
+}
+
+func decodeMultibase(s string) ([]byte, error) {
+	if s == "" || s[0] != 'z' {
+		return nil, errors.New("crypto: key must be encoded as a base58btc multibase string")
+	}
+	return base58Decode(s[1:])
+}
+
+func encodeMultibase(codec uint64, key []byte) string {
+	return "z" + base58Encode(append(binary.AppendUvarint(nil, codec), key...))
+}
@@ -0,0 +1,216 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicDIDKey(t *testing.T) {
+	type out struct {
+		curve Curve
+		err   string
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a secp256k1 did:key, When it is parsed, Then it should return a K-256 key",
+			in:   "did:key:zQ3shqwJEJyMBsBXCWyCBpUBMqxcon9oHB7mCvx4sSpMdLJwc",
+			out:  out{curve: CurveK256},
+		},
+		{
+			name: "Given a P-256 did:key, When it is parsed, Then it should return a P-256 key",
+			in:   "did:key:zDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo",
+			out:  out{curve: CurveP256},
+		},
+		{
+			name: "Given a DID of another method, When it is parsed, Then it should return an error",
+			in:   "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+			out:  out{err: `crypto: "did:plc:ewvi7nxzyoun6zhxrhs64oiz" is not a did:key`},
+		},
+		{
+			name: "Given a key not encoded in base58btc, When it is parsed, Then it should return an error",
+			in:   "did:key:bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku",
+			out:  out{err: "crypto: key must be encoded as a base58btc multibase string"},
+		},
+		{
+			name: "Given a key with invalid base58 characters, When it is parsed, Then it should return an error",
+			in:   "did:key:z0OIl",
+			out:  out{err: "crypto: invalid base58 character"},
+		},
+		{
+			name: "Given a key of an unsupported type, When it is parsed, Then it should return an error",
+			in:   "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
+			out:  out{err: "crypto: unsupported key multicodec 0xed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicDIDKey(tt.in)
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+				assert.Nil(t, key)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.curve, key.Curve())
+			assert.Equal(t, tt.in, key.DIDKey())
+		})
+	}
+}
+
+func TestParsePublicLegacyMultibase(t *testing.T) {
+	for _, curve := range []Curve{CurveK256, CurveP256} {
+		t.Run("Given a legacy "+string(curve)+" key, When it is parsed, Then it should return the same key", func(t *testing.T) {
+			priv, err := GeneratePrivateKey(curve)
+			require.NoError(t, err)
+			pub := priv.PublicKey()
+
+			key, err := ParsePublicLegacyMultibase(curve, "z"+base58Encode(pub.Bytes()))
+
+			require.NoError(t, err)
+			assert.Equal(t, pub.Bytes(), key.Bytes())
+			assert.Equal(t, pub.Multibase(), key.Multibase())
+		})
+	}
+
+	t.Run("Given a legacy uncompressed P-256 key, When it is parsed, Then it should return the same key", func(t *testing.T) {
+		priv, err := GeneratePrivateKey(CurveP256)
+		require.NoError(t, err)
+		ecdhKey, err := priv.(*privateKeyP256).key.ECDH()
+		require.NoError(t, err)
+
+		key, err := ParsePublicLegacyMultibase(CurveP256, "z"+base58Encode(ecdhKey.PublicKey().Bytes()))
+
+		require.NoError(t, err)
+		assert.Equal(t, priv.PublicKey().Bytes(), key.Bytes())
+	})
+
+	t.Run("Given an invalid key, When it is parsed, Then it should return an error", func(t *testing.T) {
+		uncompressed := make([]byte, 65)
+		uncompressed[0] = 0x04
+		_, err := ParsePublicKey(CurveP256, uncompressed)
+		assert.EqualError(t, err, "crypto: invalid p256 public key")
+
+		_, err = ParsePublicLegacyMultibase(CurveP256, "z"+base58Encode([]byte{0x02, 0x01}))
+		assert.EqualError(t, err, "crypto: invalid p256 public key")
+
+		_, err = ParsePublicKey(Curve("ed25519"), []byte{0x01})
+		assert.EqualError(t, err, `crypto: unsupported curve "ed25519"`)
+	})
+}
+
+func TestPublicKeyVerify(t *testing.T) {
+	content := []byte("signed content")
+
+	for _, curve := range []Curve{CurveK256, CurveP256} {
+		priv, err := GeneratePrivateKey(curve)
+		require.NoError(t, err)
+		other, err := GeneratePrivateKey(curve)
+		require.NoError(t, err)
+
+		sig, err := priv.Sign(content)
+		require.NoError(t, err)
+
+		type in struct {
+			key     PublicKey
+			content []byte
+			sig     []byte
+		}
+
+		tests := []struct {
+			name string
+			in   in
+			err  string
+		}{
+			{
+				name: "Given a valid " + string(curve) + " signature, When it is verified, Then it should return no error",
+				in:   in{key: priv.PublicKey(), content: content, sig: sig},
+			},
+			{
+				name: "Given a " + string(curve) + " signature of other content, When it is verified, Then it should return an error",
+				in:   in{key: priv.PublicKey(), content: []byte("other content"), sig: sig},
+				err:  "crypto: invalid signature",
+			},
+			{
+				name: "Given a " + string(curve) + " signature by another key, When it is verified, Then it should return an error",
+				in:   in{key: other.PublicKey(), content: content, sig: sig},
+				err:  "crypto: invalid signature",
+			},
+			{
+				name: "Given a high-S " + string(curve) + " signature, When it is verified, Then it should return an error",
+				in:   in{key: priv.PublicKey(), content: content, sig: highS(t, curve, sig)},
+				err:  "crypto: invalid signature: high-S signatures are not accepted",
+			},
+			{
+				name: "Given a " + string(curve) + " signature of the wrong size, When it is verified, Then it should return an error",
+				in:   in{key: priv.PublicKey(), content: content, sig: sig[:63]},
+				err:  "crypto: invalid signature: expected 64 bytes, got 63",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := tt.in.key.Verify(tt.in.content, tt.in.sig)
+				if tt.err != "" {
+					assert.ErrorIs(t, err, ErrInvalidSignature)
+					assert.EqualError(t, err, tt.err)
+					return
+				}
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
+
+// highS returns the malleated form of a low-S signature, with s replaced by n - s.
+func highS(t *testing.T, curve Curve, sig []byte) []byte {
+	order := map[Curve]string{
+		CurveK256: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		CurveP256: "FFFFFFFF00000000FFFFFFFFFFFFFFFFBCE6FAADA7179E84F3B9CAC2FC632551",
+	}[curve]
+
+	n, ok := new(big.Int).SetString(order, 16)
+	require.True(t, ok)
+	s := new(big.Int).Sub(n, new(big.Int).SetBytes(sig[32:]))
+
+	malleated := append([]byte{}, sig[:32]...)
+	return append(malleated, s.FillBytes(make([]byte, 32))...)
+}
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		out  string
+	}{
+		{
+			name: "Given bytes with leading zeros, When they are encoded, Then each zero should become a leading 1",
+			in:   []byte{0x00, 0x00, 0x28, 0x7f, 0xb4, 0xcd},
+			out:  "11233QC4",
+		},
+		{
+			name: "Given text, When it is encoded, Then it should return the bitcoin alphabet encoding",
+			in:   []byte("hello world"),
+			out:  "StV1DL6CwTryKyV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out, base58Encode(tt.in))
+
+			decoded, err := base58Decode(tt.out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.in, decoded)
+		})
+	}
+}
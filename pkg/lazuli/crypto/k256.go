@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+type publicKeyK256 struct {
+	key *secp256k1.PublicKey
+}
+
+func parsePublicKeyK256(b []byte) (PublicKey, error) {
+	key, err := secp256k1.ParsePubKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: invalid secp256k1 public key: %w", err)
+	}
+	return &publicKeyK256{key: key}, nil
+}
+
+func (k *publicKeyK256) Curve() Curve {
+	return CurveK256
+}
+
+func (k *publicKeyK256) Bytes() []byte {
+	return k.key.SerializeCompressed()
+}
+
+func (k *publicKeyK256) Verify(content, sig []byte) error {
+	if len(sig) != signatureSize {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSignature, signatureSize, len(sig))
+	}
+
+	var r, s secp256k1.ModNScalar
+	if overflow := r.SetByteSlice(sig[:32]); overflow || r.IsZero() {
+		return fmt.Errorf("%w: r is out of range", ErrInvalidSignature)
+	}
+	if overflow := s.SetByteSlice(sig[32:]); overflow || s.IsZero() {
+		return fmt.Errorf("%w: s is out of range", ErrInvalidSignature)
+	}
+	if s.IsOverHalfOrder() {
+		return fmt.Errorf("%w: high-S signatures are not accepted", ErrInvalidSignature)
+	}
+
+	hash := sha256.Sum256(content)
+	if !ecdsa.NewSignature(&r, &s).Verify(hash[:], k.key) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (k *publicKeyK256) Multibase() string {
+	return encodeMultibase(multicodecK256Pub, k.Bytes())
+}
+
+func (k *publicKeyK256) DIDKey() string {
+	return didKeyPrefix + k.Multibase()
+}
+
+type privateKeyK256 struct {
+	key *secp256k1.PrivateKey
+}
+
+func generatePrivateKeyK256() (PrivateKey, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("crypto: fail to generate secp256k1 key: %w", err)
+	}
+	return &privateKeyK256{key: key}, nil
+}
+
+func (k *privateKeyK256) PublicKey() PublicKey {
+	return &publicKeyK256{key: k.key.PubKey()}
+}
+
+func (k *privateKeyK256) Sign(content []byte) ([]byte, error) {
+	hash := sha256.Sum256(content)
+	// signatures made by ecdsa.Sign are already in the low-S form
+	sig := ecdsa.Sign(k.key, hash[:])
+	r, s := sig.R(), sig.S()
+	rb, sb := r.Bytes(), s.Bytes()
+	return append(rb[:], sb[:]...), nil
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type publicKeyP256 struct {
+	key *ecdsa.PublicKey
+}
+
+func parsePublicKeyP256(b []byte) (PublicKey, error) {
+	curve := elliptic.P256()
+
+	var x, y *big.Int
+	if len(b) > 0 && b[0] == 0x04 {
+		// the uncompressed form is only found in legacy verification methods; crypto/ecdh checks the point is on the
+		// curve before its coordinates are read
+		key, err := ecdh.P256().NewPublicKey(b)
+		if err != nil {
+			return nil, errors.New("crypto: invalid p256 public key")
+		}
+		point := key.Bytes()
+		x, y = new(big.Int).SetBytes(point[1:33]), new(big.Int).SetBytes(point[33:])
+	} else {
+		x, y = elliptic.UnmarshalCompressed(curve, b)
+	}
+	if x == nil {
+		return nil, errors.New("crypto: invalid p256 public key")
+	}
+
+	return &publicKeyP256{key: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+}
+
+func (k *publicKeyP256) Curve() Curve {
+	return CurveP256
+}
+
+func (k *publicKeyP256) Bytes() []byte {
+	return elliptic.MarshalCompressed(k.key.Curve, k.key.X, k.key.Y)
+}
+
+func (k *publicKeyP256) Verify(content, sig []byte) error {
+	if len(sig) != signatureSize {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSignature, signatureSize, len(sig))
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if s.Cmp(p256HalfOrder) > 0 {
+		return fmt.Errorf("%w: high-S signatures are not accepted", ErrInvalidSignature)
+	}
+
+	hash := sha256.Sum256(content)
+	if !ecdsa.Verify(k.key, hash[:], r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (k *publicKeyP256) Multibase() string {
+	return encodeMultibase(multicodecP256Pub, k.Bytes())
+}
+
+func (k *publicKeyP256) DIDKey() string {
+	return didKeyPrefix + k.Multibase()
+}
+
+// p256HalfOrder is half the order of the P-256 curve, the largest s value of a low-S signature.
+var p256HalfOrder = new(big.Int).Rsh(elliptic.P256().Params().N, 1)
+
+type privateKeyP256 struct {
+	key *ecdsa.PrivateKey
+}
+
+func generatePrivateKeyP256() (PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: fail to generate p256 key: %w", err)
+	}
+	return &privateKeyP256{key: key}, nil
+}
+
+func (k *privateKeyP256) PublicKey() PublicKey {
+	return &publicKeyP256{key: &k.key.PublicKey}
+}
+
+func (k *privateKeyP256) Sign(content []byte) ([]byte, error) {
+	hash := sha256.Sum256(content)
+	r, s, err := ecdsa.Sign(rand.Reader, k.key, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("crypto: fail to sign: %w", err)
+	}
+	if s.Cmp(p256HalfOrder) > 0 {
+		s.Sub(k.key.Params().N, s)
+	}
+
+	sig := make([]byte, signatureSize)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
@@ -0,0 +1,78 @@
+package lazuli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrCursorNotFound is returned by a CursorStore when there is no cursor stored.
+var ErrCursorNotFound = errors.New("cursor not found")
+
+// CursorStore persists the sequence number of the last firehose event handled, so a consumer can resume from it
+// after a restart.
+type CursorStore interface {
+	Load(ctx context.Context) (int64, error)
+	Save(ctx context.Context, cursor int64) error
+}
+
+// MemoryCursorStore keeps the cursor in memory, which is mostly useful for tests and short-lived processes.
+type MemoryCursorStore struct {
+	mu     sync.RWMutex
+	cursor *int64
+}
+
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{}
+}
+
+func (s *MemoryCursorStore) Load(_ context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cursor == nil {
+		return 0, ErrCursorNotFound
+	}
+	return *s.cursor, nil
+}
+
+func (s *MemoryCursorStore) Save(_ context.Context, cursor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursor = &cursor
+	return nil
+}
+
+// FileCursorStore keeps the cursor as text in a file, replaced atomically on every save.
+type FileCursorStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+func (s *FileCursorStore) Load(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, ErrCursorNotFound
+		}
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (s *FileCursorStore) Save(_ context.Context, cursor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeFileAtomic(s.path, []byte(strconv.FormatInt(cursor, 10)))
+}
@@ -0,0 +1,62 @@
+package lazuli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) CursorStore
+	}{
+		{
+			name: "Given a MemoryCursorStore",
+			store: func(t *testing.T) CursorStore {
+				return NewMemoryCursorStore()
+			},
+		},
+		{
+			name: "Given a FileCursorStore",
+			store: func(t *testing.T) CursorStore {
+				return NewFileCursorStore(filepath.Join(t.TempDir(), "cursor"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+", When nothing was saved, Then Load should return ErrCursorNotFound", func(t *testing.T) {
+			_, err := tt.store(t).Load(context.Background())
+
+			assert.ErrorIs(t, err, ErrCursorNotFound)
+		})
+
+		t.Run(tt.name+", When cursors are saved, Then Load should return the last one", func(t *testing.T) {
+			store := tt.store(t)
+
+			require.NoError(t, store.Save(context.Background(), 41))
+			require.NoError(t, store.Save(context.Background(), 42))
+			cursor, err := store.Load(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(42), cursor)
+		})
+	}
+}
+
+func TestFileCursorStore_Load(t *testing.T) {
+	t.Run("Given a corrupted cursor file, When Load is called, Then it should return an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cursor")
+		require.NoError(t, os.WriteFile(path, []byte("not-a-number"), 0o600))
+
+		_, err := NewFileCursorStore(path).Load(context.Background())
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCursorNotFound)
+	})
+}
@@ -1,17 +1,51 @@
 package lazuli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+// ErrExpiredToken matches errors returned by the PDS when the access token of the session has expired.
+var ErrExpiredToken = errors.New("expired token")
+
+// ErrAuthFactorTokenRequired matches errors returned by CreateSession when the account has email two-factor
+// authentication enabled. The PDS sends a code to the account email, and CreateSession must be called again with it
+// using WithAuthFactorToken.
+var ErrAuthFactorTokenRequired = errors.New("auth factor token required")
+
+// ErrFutureCursor matches the firehose error sent when the requested cursor is ahead of the stream.
+var ErrFutureCursor = errors.New("future cursor")
+
+// ErrConsumerTooSlow matches the firehose error sent when the consumer cannot keep up with the stream.
+var ErrConsumerTooSlow = errors.New("consumer too slow")
+
+// ErrInvalidSwap matches errors returned by record writes when the record or the repository changed since the CID given
+// with WithSwapRecord or WithSwapCommit.
+var ErrInvalidSwap = errors.New("invalid swap")
+
+// xrpcErrorNames maps the sentinel errors exposed by lazuli to the XRPC error names sent by the server.
+var xrpcErrorNames = map[error]string{
+	ErrExpiredToken:            "ExpiredToken",
+	ErrAuthFactorTokenRequired: "AuthFactorTokenRequired",
+	ErrFutureCursor:            "FutureCursor",
+	ErrConsumerTooSlow:         "ConsumerTooSlow",
+	ErrInvalidSwap:             "InvalidSwap",
+}
+
 type Error struct {
 	Code    int
 	Message string
 	Details string
 }
 
+type xrpcErrorBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
 func (e *Error) Error() string {
 	return fmt.Sprintf(
 		"status: %d, error: %s, details: %s",
@@ -21,6 +55,38 @@ func (e *Error) Error() string {
 	)
 }
 
+// XRPCError returns the XRPC error name (e.g. "ExpiredToken") found in the response body kept in Details, or an
+// empty string when the body is not a XRPC error.
+func (e *Error) XRPCError() string {
+	var body xrpcErrorBody
+	if err := json.Unmarshal([]byte(e.Details), &body); err != nil {
+		return ""
+	}
+	return body.Error
+}
+
+// Is allows matching the error against the lazuli sentinel errors with errors.Is.
+func (e *Error) Is(target error) bool {
+	name, ok := xrpcErrorNames[target]
+	return ok && e.XRPCError() == name
+}
+
+// StreamError is an error frame sent by the firehose right before it closes the connection.
+type StreamError struct {
+	Name    string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("stream error: %s, details: %s", e.Name, e.Message)
+}
+
+// Is allows matching the error against the lazuli sentinel errors with errors.Is.
+func (e *StreamError) Is(target error) bool {
+	name, ok := xrpcErrorNames[target]
+	return ok && e.Name == name
+}
+
 func newError(code int, message string, details string) *Error {
 	return &Error{
 		Code:    code,
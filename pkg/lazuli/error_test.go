@@ -2,6 +2,7 @@ package lazuli
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"testing"
@@ -107,3 +108,59 @@ func TestError_newErrorFromResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestError_Is(t *testing.T) {
+	type in struct {
+		err    *Error
+		target error
+	}
+
+	type out struct {
+		is bool
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given an ExpiredToken response, When errors.Is is called with ErrExpiredToken, Then it should match",
+			in: in{
+				err:    newError(http.StatusBadRequest, "test message", `{"error":"ExpiredToken","message":"Token has expired"}`),
+				target: ErrExpiredToken,
+			},
+			out: out{is: true},
+		},
+		{
+			name: "Given another XRPC error, When errors.Is is called with ErrExpiredToken, Then it should not match",
+			in: in{
+				err:    newError(http.StatusBadRequest, "test message", `{"error":"InvalidRequest"}`),
+				target: ErrExpiredToken,
+			},
+			out: out{is: false},
+		},
+		{
+			name: "Given an AuthFactorTokenRequired response, When errors.Is is called with ErrAuthFactorTokenRequired, Then it should match",
+			in: in{
+				err:    newError(http.StatusUnauthorized, "test message", `{"error":"AuthFactorTokenRequired"}`),
+				target: ErrAuthFactorTokenRequired,
+			},
+			out: out{is: true},
+		},
+		{
+			name: "Given a non JSON detail, When errors.Is is called with ErrExpiredToken, Then it should not match",
+			in: in{
+				err:    newError(http.StatusInternalServerError, "test message", "test details"),
+				target: ErrExpiredToken,
+			},
+			out: out{is: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out.is, errors.Is(tt.in.err, tt.in.target))
+		})
+	}
+}
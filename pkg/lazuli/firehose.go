@@ -3,51 +3,444 @@ package lazuli
 import (
 	"bytes"
 	"context"
-	"github.com/gorilla/websocket"
-	"io"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
 	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
 )
 
 type HandlerCommitFn func(evt bsky.CommitEvent) error
 
-// ConsumeFirehose connects to a websocket, reads messages, decodes them as repo commit events, and processes them using a handler function.
-//
-// TODO: improve firehose consumer to be more flexible
-func (c *client) ConsumeFirehose(ctx context.Context, handler HandlerCommitFn) error {
-	conn, _, err := c.wsDialer.Dial(c.wsURL, nil)
+// FirehoseOption configures optional behavior of ConsumeFirehose.
+type FirehoseOption func(cfg *firehoseConfig)
+
+type firehoseConfig struct {
+	reconnect          *ReconnectPolicy
+	onDisconnect       func(err error)
+	onReconnect        func(attempt int)
+	cursor             *int64
+	cursorStore        CursorStore
+	checkpointEvents   int
+	checkpointInterval time.Duration
+}
+
+// ReconnectPolicy controls how ConsumeFirehose reconnects after losing the connection. The wait between attempts
+// grows exponentially from InitialInterval up to MaxInterval, randomized by Jitter. A zero InitialInterval,
+// MaxInterval or Multiplier is taken from DefaultReconnectPolicy, so a partial policy never reconnects in a tight loop.
+type ReconnectPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter is the randomization factor applied to each interval, between 0 (no randomization) and 1.
+	Jitter float64
+	// MaxRetries is the number of consecutive failed attempts before giving up, zero meaning no limit.
+	MaxRetries int
+	// MaxElapsedTime is how long to keep trying since the connection was lost, zero meaning no limit.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultReconnectPolicy retries forever, waiting from 1 second up to 1 minute between attempts.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialInterval: time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2,
+		Jitter:          0.5,
+	}
+}
+
+// WithReconnect makes ConsumeFirehose reconnect when the connection is lost, resuming from the sequence number of
+// the last event handled successfully. Without it, ConsumeFirehose returns once the connection is closed.
+func WithReconnect(policy ReconnectPolicy) FirehoseOption {
+	return func(cfg *firehoseConfig) {
+		cfg.reconnect = &policy
+	}
+}
+
+// WithOnDisconnect sets a function called with the cause every time an established firehose connection is lost and
+// a reconnection is going to be attempted.
+func WithOnDisconnect(fn func(err error)) FirehoseOption {
+	return func(cfg *firehoseConfig) {
+		cfg.onDisconnect = fn
+	}
+}
+
+// WithOnReconnect sets a function called every time the firehose connection is established again, with the number of
+// attempts it took.
+func WithOnReconnect(fn func(attempt int)) FirehoseOption {
+	return func(cfg *firehoseConfig) {
+		cfg.onReconnect = fn
+	}
+}
+
+// WithCursor makes ConsumeFirehose start from the events after the given sequence number instead of live, taking
+// precedence over the cursor loaded from a CursorStore.
+func WithCursor(cursor int64) FirehoseOption {
+	return func(cfg *firehoseConfig) {
+		cfg.cursor = &cursor
+	}
+}
+
+// WithCursorStore makes ConsumeFirehose start from the cursor saved in store, and save the sequence number of the
+// last event handled every given number of events or every given interval, whichever happens first. The interval is
+// checked even while no event arrives, so a quiet stream does not hold the cursor back. A zero value disables the
+// respective trigger, and the cursor is also saved when ConsumeFirehose returns.
+func WithCursorStore(store CursorStore, everyEvents int, every time.Duration) FirehoseOption {
+	return func(cfg *firehoseConfig) {
+		cfg.cursorStore = store
+		cfg.checkpointEvents = everyEvents
+		cfg.checkpointInterval = every
+	}
+}
+
+// interval returns how long to wait before the given reconnection attempt, starting at 1.
+func (p ReconnectPolicy) interval(attempt int) time.Duration {
+	defaults := DefaultReconnectPolicy()
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = defaults.InitialInterval
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = defaults.MaxInterval
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = defaults.Multiplier
+	}
+
+	interval := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		// #nosec G404 -- the jitter only spreads reconnections and does not need a secure source
+		interval *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(interval)
+}
+
+// disconnectError wraps errors after which the firehose connection can be opened again.
+type disconnectError struct {
+	err error
+}
+
+func (e *disconnectError) Error() string {
+	return e.err.Error()
+}
+
+func (e *disconnectError) Unwrap() error {
+	return e.err
+}
+
+// sequencedEvent is implemented by the events that carry a sequence number of the stream.
+type sequencedEvent interface {
+	GetSeq() int64
+}
+
+// firehoseState is kept across the connections of a single ConsumeFirehose call.
+type firehoseState struct {
+	cfg       *firehoseConfig
+	cursor    *int64
+	connected bool
+	onConnect func()
+	pending   int
+}
+
+// handled moves the cursor to the sequence number of an event handled successfully, saving it to the cursor store
+// when enough events were handled since the last save. Saves after the checkpoint interval are driven by
+// checkpointTicker instead.
+func (s *firehoseState) handled(ctx context.Context, seq int64) error {
+	s.cursor = &seq
+	if s.cfg.cursorStore == nil {
+		return nil
+	}
+
+	s.pending++
+	if s.cfg.checkpointEvents > 0 && s.pending >= s.cfg.checkpointEvents {
+		return s.checkpoint(ctx)
+	}
+	return nil
+}
+
+// checkpointTicker returns a ticker firing at the checkpoint interval, or nil when there is none.
+func (s *firehoseState) checkpointTicker() *time.Ticker {
+	if s.cfg.cursorStore == nil || s.cfg.checkpointInterval <= 0 {
+		return nil
+	}
+	return time.NewTicker(s.cfg.checkpointInterval)
+}
+
+// checkpoint saves the cursor to the cursor store when there are events handled since the last save.
+func (s *firehoseState) checkpoint(ctx context.Context) error {
+	if s.cfg.cursorStore == nil || s.pending == 0 || s.cursor == nil {
+		return nil
+	}
+	if err := s.cfg.cursorStore.Save(ctx, *s.cursor); err != nil {
+		return newError(http.StatusInternalServerError, "fail to save firehose cursor", err.Error())
+	}
+	s.pending = 0
+	return nil
+}
+
+// ConsumeFirehose connects to a websocket, reads messages, decodes each of them as the event of its kind (commit, identity,
+// account, handle, tombstone or info), and processes them using a handler function. Error frames sent by the server are
+// returned as *StreamError. When ctx is canceled the connection is closed and ctx.Err() is returned.
+func (c *client) ConsumeFirehose(ctx context.Context, handler HandlerCommitFn, opts ...FirehoseOption) error {
+	var cfg firehoseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	state := &firehoseState{cfg: &cfg, cursor: cfg.cursor}
+	if state.cursor == nil && cfg.cursorStore != nil {
+		cursor, err := cfg.cursorStore.Load(ctx)
+		if err != nil && !errors.Is(err, ErrCursorNotFound) {
+			return newError(http.StatusInternalServerError, "fail to load firehose cursor", err.Error())
+		}
+		if err == nil {
+			state.cursor = &cursor
+		}
+	}
+
+	var err error
+	if cfg.reconnect == nil {
+		err = c.consumeFirehoseConnection(ctx, handler, state)
+		var disconnect *disconnectError
+		if errors.As(err, &disconnect) {
+			err = disconnect.err
+			if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				err = nil
+			}
+		}
+	} else {
+		err = c.consumeFirehoseWithReconnect(ctx, handler, state, &cfg)
+	}
+
+	// ctx may be canceled already, but the events handled so far must still be saved.
+	if checkpointErr := state.checkpoint(context.WithoutCancel(ctx)); checkpointErr != nil && err == nil {
+		err = checkpointErr
+	}
+
+	return err
+}
+
+func (c *client) consumeFirehoseWithReconnect(ctx context.Context, handler HandlerCommitFn, state *firehoseState, cfg *firehoseConfig) error {
+	policy := cfg.reconnect
+	attempt := 0
+	disconnectedAt := time.Now()
+	state.onConnect = func() {
+		if attempt > 0 && cfg.onReconnect != nil {
+			cfg.onReconnect(attempt)
+		}
+		attempt = 0
+	}
+
+	for {
+		state.connected = false
+		err := c.consumeFirehoseConnection(ctx, handler, state)
+
+		var disconnect *disconnectError
+		if !errors.As(err, &disconnect) {
+			return err
+		}
+		if state.connected {
+			disconnectedAt = time.Now()
+			if cfg.onDisconnect != nil {
+				cfg.onDisconnect(disconnect.err)
+			}
+		}
+
+		attempt++
+		if policy.MaxRetries > 0 && attempt > policy.MaxRetries {
+			return newError(http.StatusInternalServerError, "fail to reconnect to websocket", fmt.Sprintf("gave up after %d attempts: %s", policy.MaxRetries, disconnect.err.Error()))
+		}
+		if policy.MaxElapsedTime > 0 && time.Since(disconnectedAt) > policy.MaxElapsedTime {
+			return newError(http.StatusInternalServerError, "fail to reconnect to websocket", fmt.Sprintf("gave up after %s: %s", policy.MaxElapsedTime, disconnect.err.Error()))
+		}
+
+		timer := time.NewTimer(policy.interval(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// consumeFirehoseConnection reads a single firehose connection until it fails. Errors after which it is possible to
+// connect again are returned as *disconnectError.
+func (c *client) consumeFirehoseConnection(ctx context.Context, handler HandlerCommitFn, state *firehoseState) error {
+	reqURL, err := c.firehoseURL(state.cursor)
 	if err != nil {
 		return newError(http.StatusInternalServerError, "fail to connect to websocket", err.Error())
 	}
+
+	conn, _, err := c.wsDialer.DialContext(ctx, reqURL, c.requestHeader())
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &disconnectError{err: newError(http.StatusInternalServerError, "fail to connect to websocket", err.Error())}
+	}
 	defer conn.Close()
 
+	state.connected = true
+	if state.onConnect != nil {
+		state.onConnect()
+	}
+
+	// ReadMessage does not watch ctx, so closing the connection is what unblocks it on cancellation.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	// messages are read in their own goroutine, so the cursor can be saved at the checkpoint interval while ReadMessage
+	// waits for the next one; events are still handled one at a time by this goroutine.
+	type frame struct {
+		message []byte
+		err     error
+	}
+	frames := make(chan frame)
+	go func() {
+		for {
+			_, message, err := conn.ReadMessage()
+			select {
+			case frames <- frame{message: message, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var tick <-chan time.Time
+	if ticker := state.checkpointTicker(); ticker != nil {
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
 	for {
-		_, message, errMessage := conn.ReadMessage()
+		var message []byte
+		var errMessage error
+		select {
+		case <-tick:
+			if checkpointErr := state.checkpoint(ctx); checkpointErr != nil {
+				return checkpointErr
+			}
+			continue
+		case f := <-frames:
+			message, errMessage = f.message, f.err
+		}
 		if errMessage != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if websocket.IsCloseError(errMessage, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				// TODO: for now lets close firehose, but then improve to restart the websocket.
-				return nil
+				return &disconnectError{err: errMessage}
 			}
-			return newError(http.StatusInternalServerError, "fail to read message from websocket", errMessage.Error())
+			return &disconnectError{err: newError(http.StatusInternalServerError, "fail to read message from websocket", errMessage.Error())}
 		}
 
-		decoder := cbor.NewDecoder(bytes.NewReader(message))
-
-		for {
-			var evt bsky.RepoCommitEvent
-			decodeErr := decoder.Decode(&evt)
-			if decodeErr != nil {
-				if decodeErr == io.EOF {
-					break
-				}
-				return newError(http.StatusInternalServerError, "fail to decode repo commit event message", decodeErr.Error())
+		evt, decodeErr := decodeFirehoseFrame(message)
+		if decodeErr != nil {
+			var streamErr *StreamError
+			if errors.As(decodeErr, &streamErr) && errors.Is(streamErr, ErrConsumerTooSlow) {
+				return &disconnectError{err: streamErr}
 			}
+			return decodeErr
+		}
+		if evt == nil {
+			continue
+		}
+
+		if handleErr := handler(evt); handleErr != nil {
+			return handleErr
+		}
 
-			if handleErr := handler(evt); handleErr != nil {
-				return handleErr
+		if seqEvt, ok := evt.(sequencedEvent); ok && seqEvt.GetSeq() > 0 {
+			if checkpointErr := state.handled(ctx, seqEvt.GetSeq()); checkpointErr != nil {
+				return checkpointErr
 			}
 		}
 	}
 }
+
+// decodeFirehoseFrame decodes a frame made of a header followed by a message body. Error frames are returned as
+// *StreamError, and a nil event is returned for message kinds not known by lazuli.
+func decodeFirehoseFrame(frame []byte) (bsky.CommitEvent, error) {
+	decoder := cbor.NewDecoder(bytes.NewReader(frame))
+
+	var header bsky.EventStreamHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to decode event stream frame header", err.Error())
+	}
+
+	switch header.Op {
+	case bsky.EventStreamOpError:
+		var body bsky.EventStreamError
+		if err := decoder.Decode(&body); err != nil {
+			return nil, newError(http.StatusInternalServerError, "fail to decode event stream error", err.Error())
+		}
+		return nil, &StreamError{Name: body.Error, Message: body.Message}
+	case bsky.EventStreamOpMessage:
+	default:
+		return nil, nil
+	}
+
+	switch header.Type {
+	case "#commit":
+		return decodeFirehoseMessage[bsky.RepoCommitEvent](decoder, "repo commit")
+	case "#identity":
+		return decodeFirehoseMessage[bsky.RepoIdentityEvent](decoder, "repo identity")
+	case "#account":
+		return decodeFirehoseMessage[bsky.RepoAccountEvent](decoder, "repo account")
+	case "#handle":
+		return decodeFirehoseMessage[bsky.RepoHandleEvent](decoder, "repo handle")
+	case "#tombstone":
+		return decodeFirehoseMessage[bsky.RepoTombstoneEvent](decoder, "repo tombstone")
+	case "#info":
+		return decodeFirehoseMessage[bsky.RepoInfoEvent](decoder, "repo info")
+	default:
+		return nil, nil
+	}
+}
+
+func decodeFirehoseMessage[T bsky.CommitEvent](decoder *cbor.Decoder, name string) (bsky.CommitEvent, error) {
+	var evt T
+	if err := decoder.Decode(&evt); err != nil {
+		return nil, newError(http.StatusInternalServerError, fmt.Sprintf("fail to decode %s event message", name), err.Error())
+	}
+	return evt, nil
+}
+
+// firehoseURL returns the websocket URL to dial, with the cursor to resume from when there is one.
+func (c *client) firehoseURL(cursor *int64) (string, error) {
+	if cursor == nil {
+		return c.wsURL, nil
+	}
+
+	u, err := url.Parse(c.wsURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("cursor", strconv.FormatInt(*cursor, 10))
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
 	"github.com/fxamacker/cbor/v2"
@@ -14,6 +15,23 @@ import (
 	"net/http/httptest"
 )
 
+// marshalFrame encodes an event as an event stream frame, with the header followed by the event.
+func marshalFrame(t *testing.T, evt bsky.CommitEvent) []byte {
+	types := map[bsky.CommitEventType]string{
+		bsky.CommitEventTypeRepoCommit:    "#commit",
+		bsky.CommitEventTypeRepoIdentity:  "#identity",
+		bsky.CommitEventTypeRepoAccount:   "#account",
+		bsky.CommitEventTypeRepoHandle:    "#handle",
+		bsky.CommitEventTypeRepoTombstone: "#tombstone",
+		bsky.CommitEventTypeRepoInfo:      "#info",
+	}
+	header, err := cbor.Marshal(bsky.EventStreamHeader{Op: bsky.EventStreamOpMessage, Type: types[evt.Type()]})
+	require.NoError(t, err)
+	body, err := cbor.Marshal(evt)
+	require.NoError(t, err)
+	return append(header, body...)
+}
+
 // MockHandlerCommitFn is a mock implementation of HandlerCommitFn.
 type MockHandlerCommitFn struct {
 	mock.Mock
@@ -122,14 +140,13 @@ func TestClient_ConsumeFirehose(t *testing.T) {
 					defer conn.Close()
 
 					for _, event := range tt.in.events {
-						message, cborErr := cbor.Marshal(event)
-						require.NoError(t, cborErr)
-						writeErr := conn.WriteMessage(websocket.BinaryMessage, message)
+						writeErr := conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, event))
 						require.NoError(t, writeErr)
 					}
 
 					if tt.in.shouldBreakCBORDecoder {
-						err = conn.WriteMessage(websocket.BinaryMessage, []byte{'\xFF'}) // Invalid CBOR to trigger decode error
+						header, _ := cbor.Marshal(bsky.EventStreamHeader{Op: bsky.EventStreamOpMessage, Type: "#commit"})
+						err = conn.WriteMessage(websocket.BinaryMessage, append(header, '\xFF')) // Invalid CBOR to trigger decode error
 						require.NoError(t, err)
 					}
 				}))
@@ -166,3 +183,417 @@ func TestClient_ConsumeFirehose(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ConsumeFirehose_contextCancellation(t *testing.T) {
+	t.Run("Given an open firehose connection, When the context is canceled, Then it should close the connection and return the context error", func(t *testing.T) {
+		serverDone := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+			defer close(serverDone)
+
+			// blocks until the client closes the connection
+			_, _, _ = conn.ReadMessage()
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:],
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		err := lazuliClient.ConsumeFirehose(ctx, func(evt bsky.CommitEvent) error { return nil })
+
+		assert.ErrorIs(t, err, context.Canceled)
+		<-serverDone
+	})
+
+	t.Run("Given a canceled context, When ConsumeFirehose is called, Then it should not connect and return the context error", func(t *testing.T) {
+		lazuliClient := &client{
+			wsURL:    "ws://127.0.0.1:1",
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := lazuliClient.ConsumeFirehose(ctx, func(evt bsky.CommitEvent) error { return nil })
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestClient_ConsumeFirehose_withReconnect(t *testing.T) {
+	policy := ReconnectPolicy{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Multiplier: 2}
+
+	t.Run("Given a dropped connection, When reconnect is enabled, Then it should reconnect from the last handled sequence number", func(t *testing.T) {
+		var cursors []string
+		connections := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			connections++
+			cursors = append(cursors, r.URL.Query().Get("cursor"))
+
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, bsky.RepoCommitEvent{Seq: int64(connections * 10)})))
+			// returning closes the connection without a close frame, like a relay going away abruptly
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:],
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var seqs []int64
+		var disconnects, reconnects int
+		handler := func(evt bsky.CommitEvent) error {
+			seqs = append(seqs, evt.(bsky.RepoCommitEvent).Seq)
+			if len(seqs) == 3 {
+				cancel()
+			}
+			return nil
+		}
+
+		err := lazuliClient.ConsumeFirehose(ctx, handler,
+			WithReconnect(policy),
+			WithOnDisconnect(func(err error) { disconnects++ }),
+			WithOnReconnect(func(attempt int) { reconnects++ }),
+		)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []int64{10, 20, 30}, seqs)
+		assert.Equal(t, []string{"", "10", "20"}, cursors)
+		assert.Equal(t, 2, disconnects)
+		assert.Equal(t, 2, reconnects)
+	})
+
+	t.Run("Given an unreachable firehose, When the max retries are reached, Then it should give up with an error", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		wsURL := "ws" + server.URL[4:]
+		server.Close()
+
+		lazuliClient := &client{
+			wsURL:    wsURL,
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		limited := policy
+		limited.MaxRetries = 2
+		err := lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error { return nil }, WithReconnect(limited))
+
+		var lazuliErr *Error
+		require.ErrorAs(t, err, &lazuliErr)
+		assert.Equal(t, "fail to reconnect to websocket", lazuliErr.Message)
+		assert.Contains(t, lazuliErr.Details, "gave up after 2 attempts")
+	})
+
+	t.Run("Given a handler error, When reconnect is enabled, Then it should return the handler error without reconnecting", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, bsky.RepoCommitEvent{Seq: 1})))
+			_, _, _ = conn.ReadMessage()
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:],
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		handlerErr := newError(http.StatusInternalServerError, "handler error", "handler error")
+		err := lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error { return handlerErr }, WithReconnect(policy))
+
+		assert.Equal(t, handlerErr, err)
+	})
+}
+
+func TestReconnectPolicy_interval(t *testing.T) {
+	type in struct {
+		policy  ReconnectPolicy
+		attempt int
+	}
+
+	type out struct {
+		min time.Duration
+		max time.Duration
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given the first attempt, When interval is called, Then it should return the initial interval",
+			in:   in{policy: ReconnectPolicy{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2}, attempt: 1},
+			out:  out{min: time.Second, max: time.Second},
+		},
+		{
+			name: "Given a later attempt, When interval is called, Then it should grow exponentially",
+			in:   in{policy: ReconnectPolicy{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2}, attempt: 4},
+			out:  out{min: 8 * time.Second, max: 8 * time.Second},
+		},
+		{
+			name: "Given many attempts, When interval is called, Then it should be capped by the max interval",
+			in:   in{policy: ReconnectPolicy{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2}, attempt: 100},
+			out:  out{min: time.Minute, max: time.Minute},
+		},
+		{
+			name: "Given a jitter, When interval is called, Then it should randomize the interval within the jitter range",
+			in:   in{policy: ReconnectPolicy{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2, Jitter: 0.5}, attempt: 2},
+			out:  out{min: time.Second, max: 3 * time.Second},
+		},
+		{
+			name: "Given a zero value policy, When interval is called, Then it should use the default intervals",
+			in:   in{policy: ReconnectPolicy{}, attempt: 2},
+			out:  out{min: 2 * time.Second, max: 2 * time.Second},
+		},
+		{
+			name: "Given a policy with only a retry limit, When interval is called, Then it should be capped by the default max interval",
+			in:   in{policy: ReconnectPolicy{MaxRetries: 5}, attempt: 100},
+			out:  out{min: time.Minute, max: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in.policy.interval(tt.in.attempt)
+			assert.GreaterOrEqual(t, got, tt.out.min)
+			assert.LessOrEqual(t, got, tt.out.max)
+		})
+	}
+}
+
+func TestClient_ConsumeFirehose_withCursor(t *testing.T) {
+	newServer := func(t *testing.T, cursors chan<- string, seqs ...int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			cursors <- r.URL.Query().Get("cursor")
+			for _, seq := range seqs {
+				require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, bsky.RepoCommitEvent{Seq: seq})))
+			}
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+		}))
+	}
+
+	t.Run("Given a cursor, When ConsumeFirehose is called, Then it should connect from the cursor", func(t *testing.T) {
+		cursors := make(chan string, 1)
+		server := newServer(t, cursors)
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:] + "/xrpc/com.atproto.sync.subscribeRepos",
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		err := lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error { return nil }, WithCursor(1234))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1234", <-cursors)
+	})
+
+	t.Run("Given a cursor store, When ConsumeFirehose is called, Then it should resume from the stored cursor and checkpoint the handled events", func(t *testing.T) {
+		cursors := make(chan string, 1)
+		server := newServer(t, cursors, 11, 12, 13)
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:],
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		store := NewMemoryCursorStore()
+		require.NoError(t, store.Save(context.Background(), 10))
+
+		var saved []int64
+		handler := func(evt bsky.CommitEvent) error {
+			cursor, _ := store.Load(context.Background())
+			saved = append(saved, cursor)
+			return nil
+		}
+
+		err := lazuliClient.ConsumeFirehose(context.Background(), handler, WithCursorStore(store, 2, 0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "10", <-cursors)
+		assert.Equal(t, []int64{10, 10, 12}, saved)
+		cursor, _ := store.Load(context.Background())
+		assert.Equal(t, int64(13), cursor)
+	})
+
+	t.Run("Given a checkpoint interval, When no event arrives after the handled ones, Then it should still save the cursor", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, bsky.RepoCommitEvent{Seq: 21})))
+			<-release
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			wsURL:    "ws" + server.URL[4:],
+			wsDialer: websocket.DefaultDialer,
+		}
+
+		store := NewMemoryCursorStore()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error { return nil }, WithCursorStore(store, 0, 10*time.Millisecond))
+		}()
+
+		assert.Eventually(t, func() bool {
+			cursor, err := store.Load(context.Background())
+			return err == nil && cursor == 21
+		}, time.Second, 5*time.Millisecond)
+		close(release)
+		assert.NoError(t, <-errs)
+	})
+}
+
+func TestDecodeFirehoseFrame(t *testing.T) {
+	handle := "test.handle"
+	frame := func(header bsky.EventStreamHeader, body any) []byte {
+		h, err := cbor.Marshal(header)
+		require.NoError(t, err)
+		b, err := cbor.Marshal(body)
+		require.NoError(t, err)
+		return append(h, b...)
+	}
+
+	type out struct {
+		evt bsky.CommitEvent
+		err error
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		out   out
+	}{
+		{
+			name:  "Given a commit frame, When it is decoded, Then it should return a RepoCommitEvent",
+			frame: marshalFrame(t, bsky.RepoCommitEvent{Seq: 1, Repo: "did:plc:test"}),
+			out:   out{evt: bsky.RepoCommitEvent{Seq: 1, Repo: "did:plc:test"}},
+		},
+		{
+			name:  "Given an identity frame, When it is decoded, Then it should return a RepoIdentityEvent",
+			frame: marshalFrame(t, bsky.RepoIdentityEvent{Seq: 2, DID: "did:plc:test", Handle: &handle}),
+			out:   out{evt: bsky.RepoIdentityEvent{Seq: 2, DID: "did:plc:test", Handle: &handle}},
+		},
+		{
+			name:  "Given an account frame, When it is decoded, Then it should return a RepoAccountEvent",
+			frame: marshalFrame(t, bsky.RepoAccountEvent{Seq: 3, DID: "did:plc:test", Status: "takendown"}),
+			out:   out{evt: bsky.RepoAccountEvent{Seq: 3, DID: "did:plc:test", Status: "takendown"}},
+		},
+		{
+			name:  "Given a handle frame, When it is decoded, Then it should return a RepoHandleEvent",
+			frame: marshalFrame(t, bsky.RepoHandleEvent{Seq: 4, DID: "did:plc:test", Handle: handle}),
+			out:   out{evt: bsky.RepoHandleEvent{Seq: 4, DID: "did:plc:test", Handle: handle}},
+		},
+		{
+			name:  "Given a tombstone frame, When it is decoded, Then it should return a RepoTombstoneEvent",
+			frame: marshalFrame(t, bsky.RepoTombstoneEvent{Seq: 5, DID: "did:plc:test"}),
+			out:   out{evt: bsky.RepoTombstoneEvent{Seq: 5, DID: "did:plc:test"}},
+		},
+		{
+			name:  "Given an info frame, When it is decoded, Then it should return a RepoInfoEvent",
+			frame: marshalFrame(t, bsky.RepoInfoEvent{Name: "OutdatedCursor"}),
+			out:   out{evt: bsky.RepoInfoEvent{Name: "OutdatedCursor"}},
+		},
+		{
+			name:  "Given a frame of an unknown kind, When it is decoded, Then it should be skipped",
+			frame: frame(bsky.EventStreamHeader{Op: bsky.EventStreamOpMessage, Type: "#unknown"}, map[string]string{"foo": "bar"}),
+			out:   out{},
+		},
+		{
+			name:  "Given an error frame, When it is decoded, Then it should return a StreamError",
+			frame: frame(bsky.EventStreamHeader{Op: bsky.EventStreamOpError}, bsky.EventStreamError{Error: "FutureCursor", Message: "Cursor in the future."}),
+			out:   out{err: &StreamError{Name: "FutureCursor", Message: "Cursor in the future."}},
+		},
+		{
+			name:  "Given an invalid header, When it is decoded, Then it should return an error",
+			frame: []byte{'\xFF'},
+			out:   out{err: newError(http.StatusInternalServerError, "fail to decode event stream frame header", `cbor: unexpected "break" code`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := decodeFirehoseFrame(tt.frame)
+
+			assert.Equal(t, tt.out.evt, evt)
+			assert.Equal(t, tt.out.err, err)
+		})
+	}
+}
+
+func TestClient_ConsumeFirehose_errorFrames(t *testing.T) {
+	newServer := func(t *testing.T, name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, marshalFrame(t, bsky.RepoCommitEvent{Seq: 1})))
+			header, _ := cbor.Marshal(bsky.EventStreamHeader{Op: bsky.EventStreamOpError})
+			body, _ := cbor.Marshal(bsky.EventStreamError{Error: name})
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append(header, body...)))
+		}))
+	}
+
+	t.Run("Given a FutureCursor error frame, When ConsumeFirehose is called, Then it should return ErrFutureCursor", func(t *testing.T) {
+		server := newServer(t, "FutureCursor")
+		defer server.Close()
+
+		lazuliClient := &client{wsURL: "ws" + server.URL[4:], wsDialer: websocket.DefaultDialer}
+
+		err := lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error { return nil })
+
+		assert.ErrorIs(t, err, ErrFutureCursor)
+	})
+
+	t.Run("Given a ConsumerTooSlow error frame, When reconnect is enabled, Then it should reconnect", func(t *testing.T) {
+		server := newServer(t, "ConsumerTooSlow")
+		defer server.Close()
+
+		lazuliClient := &client{wsURL: "ws" + server.URL[4:], wsDialer: websocket.DefaultDialer}
+
+		var disconnectErr error
+		policy := ReconnectPolicy{InitialInterval: time.Millisecond, MaxRetries: 1}
+		err := lazuliClient.ConsumeFirehose(context.Background(), func(evt bsky.CommitEvent) error {
+			if disconnectErr != nil {
+				return newError(http.StatusInternalServerError, "reconnected", "reconnected")
+			}
+			return nil
+		}, WithReconnect(policy), WithOnDisconnect(func(err error) { disconnectErr = err }))
+
+		assert.ErrorIs(t, disconnectErr, ErrConsumerTooSlow)
+		assert.Equal(t, newError(http.StatusInternalServerError, "reconnected", "reconnected"), err)
+	})
+}
@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"container/list"
+	"time"
+)
+
+// lruCache holds at most size entries, dropping the least recently used one when it is full, so caching the
+// identities seen on the firehose does not grow without bound. It is not safe for concurrent use.
+type lruCache[T any] struct {
+	size    int
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently used.
+	order *list.List
+}
+
+type cacheEntry[T any] struct {
+	key     string
+	value   T
+	err     error
+	expires time.Time
+}
+
+func newLRUCache[T any](size int) *lruCache[T] {
+	return &lruCache[T]{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the entry of key unless it has expired at now, in which case it is removed.
+func (c *lruCache[T]) get(key string, now time.Time) (cacheEntry[T], bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return cacheEntry[T]{}, false
+	}
+
+	entry := elem.Value.(cacheEntry[T])
+	if now.After(entry.expires) {
+		c.removeElement(elem)
+		return cacheEntry[T]{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+// put adds or replaces the entry of its key, removing the least recently used entry when the cache is full.
+func (c *lruCache[T]) put(entry cacheEntry[T]) {
+	if c.size <= 0 {
+		return
+	}
+
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.size {
+		c.removeElement(c.order.Back())
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+}
+
+func (c *lruCache[T]) remove(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// removeFunc removes every entry for which fn returns true.
+func (c *lruCache[T]) removeFunc(fn func(entry cacheEntry[T]) bool) {
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if fn(elem.Value.(cacheEntry[T])) {
+			c.removeElement(elem)
+		}
+		elem = next
+	}
+}
+
+func (c *lruCache[T]) len() int {
+	return c.order.Len()
+}
+
+func (c *lruCache[T]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(cacheEntry[T]).key)
+}
@@ -0,0 +1,76 @@
+package identity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	now := time.Now()
+	entry := func(key string) cacheEntry[string] {
+		return cacheEntry[string]{key: key, value: "did:plc:" + key, expires: now.Add(time.Hour)}
+	}
+
+	t.Run("Given a full cache, When an entry is added, Then the least recently used entry should be dropped", func(t *testing.T) {
+		c := newLRUCache[string](2)
+		c.put(entry("a"))
+		c.put(entry("b"))
+		_, _ = c.get("a", now)
+
+		c.put(entry("c"))
+
+		assert.Equal(t, 2, c.len())
+		_, ok := c.get("b", now)
+		assert.False(t, ok)
+		got, ok := c.get("a", now)
+		assert.True(t, ok)
+		assert.Equal(t, "did:plc:a", got.value)
+	})
+
+	t.Run("Given an entry added again, When the cache is full, Then it should be replaced without dropping another", func(t *testing.T) {
+		c := newLRUCache[string](2)
+		c.put(entry("a"))
+		c.put(entry("b"))
+
+		replaced := entry("a")
+		replaced.value = "did:plc:other"
+		c.put(replaced)
+
+		assert.Equal(t, 2, c.len())
+		got, _ := c.get("a", now)
+		assert.Equal(t, "did:plc:other", got.value)
+	})
+
+	t.Run("Given an expired entry, When it is read, Then it should be removed", func(t *testing.T) {
+		c := newLRUCache[string](2)
+		c.put(entry("a"))
+
+		_, ok := c.get("a", now.Add(2*time.Hour))
+
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.len())
+	})
+
+	t.Run("Given entries matching a condition, When they are removed, Then the other entries should be kept", func(t *testing.T) {
+		c := newLRUCache[string](3)
+		c.put(entry("a"))
+		c.put(entry("b"))
+		c.put(entry("c"))
+
+		c.removeFunc(func(e cacheEntry[string]) bool { return e.key != "b" })
+		c.remove("missing")
+
+		assert.Equal(t, 1, c.len())
+		_, ok := c.get("b", now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Given a cache of zero size, When an entry is added, Then it should not be kept", func(t *testing.T) {
+		c := newLRUCache[string](0)
+		c.put(entry("a"))
+
+		assert.Equal(t, 0, c.len())
+	})
+}
@@ -0,0 +1,128 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+)
+
+var (
+	// ErrDIDNotFound is returned when a DID has no document, or when its document was deactivated.
+	ErrDIDNotFound = errors.New("identity: did not found")
+	// ErrUnsupportedDID is returned for DIDs of methods other than did:plc and did:web.
+	ErrUnsupportedDID = errors.New("identity: unsupported did method")
+)
+
+const (
+	// DefaultPLCURL is the URL of the public PLC directory.
+	DefaultPLCURL = "https://plc.directory"
+	// maxDIDDocSize limits the body read from DID document endpoints.
+	maxDIDDocSize = 1 << 20
+)
+
+// DIDResolverOption changes how a DIDResolver resolves DIDs.
+type DIDResolverOption func(r *DIDResolver)
+
+// WithPLCURL makes the resolver fetch did:plc documents from the PLC directory at plcURL instead of DefaultPLCURL.
+func WithPLCURL(plcURL string) DIDResolverOption {
+	return func(r *DIDResolver) {
+		r.plcURL = strings.TrimSuffix(plcURL, "/")
+	}
+}
+
+// WithDIDHTTPClient makes the resolver send its HTTP requests with httpClient.
+func WithDIDHTTPClient(httpClient *http.Client) DIDResolverOption {
+	return func(r *DIDResolver) {
+		r.httpClient = httpClient
+	}
+}
+
+// DIDResolver resolves did:plc DIDs with a PLC directory and did:web DIDs with the /.well-known/did.json endpoint of
+// their domain.
+type DIDResolver struct {
+	plcURL     string
+	httpClient *http.Client
+}
+
+// NewDIDResolver creates a DID resolver.
+func NewDIDResolver(opts ...DIDResolverOption) *DIDResolver {
+	r := &DIDResolver{plcURL: DefaultPLCURL}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.httpClient == nil {
+		r.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return r
+}
+
+// ResolveDID returns the DID document of did.
+func (r *DIDResolver) ResolveDID(ctx context.Context, did string) (*bsky.DIDDoc, error) {
+	var docURL string
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		docURL = fmt.Sprintf("%s/%s", r.plcURL, url.PathEscape(did))
+	case strings.HasPrefix(did, "did:web:"):
+		host, err := webDIDHost(did)
+		if err != nil {
+			return nil, err
+		}
+		docURL = fmt.Sprintf("https://%s/.well-known/did.json", host)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDID, did)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", docURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("identity: fail to create did request: %w", err)
+	}
+	req.Header.Set("Accept", "application/did+ld+json, application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("identity: fail to resolve %s: %w", did, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: %s", ErrDIDNotFound, did)
+	default:
+		return nil, fmt.Errorf("identity: fail to resolve %s: unexpected status %d", did, resp.StatusCode)
+	}
+
+	var doc bsky.DIDDoc
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDIDDocSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("identity: fail to decode document of %s: %w", did, err)
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("identity: document of %s is for %s", did, doc.ID)
+	}
+
+	return &doc, nil
+}
+
+// webDIDHost returns the host of a did:web DID, which may hold a percent-encoded port. DIDs with a path are not
+// supported by the AT Protocol.
+func webDIDHost(did string) (string, error) {
+	id := strings.TrimPrefix(did, "did:web:")
+	if id == "" || strings.Contains(id, ":") {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedDID, did)
+	}
+
+	host, err := url.PathUnescape(id)
+	if err != nil || strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedDID, did)
+	}
+	return host, nil
+}
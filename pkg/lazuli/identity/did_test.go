@@ -0,0 +1,147 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plcDocument = `{
+	"@context": ["https://www.w3.org/ns/did/v1", "https://w3id.org/security/multikey/v1"],
+	"id": "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+	"alsoKnownAs": ["at://atproto.com"],
+	"verificationMethod": [{
+		"id": "did:plc:ewvi7nxzyoun6zhxrhs64oiz#atproto",
+		"type": "Multikey",
+		"controller": "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+		"publicKeyMultibase": "zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w"
+	}],
+	"service": [{
+		"id": "#atproto_pds",
+		"type": "AtprotoPersonalDataServer",
+		"serviceEndpoint": "https://enoki.us-east.host.bsky.network"
+	}]
+}`
+
+func TestDIDResolver_ResolveDID(t *testing.T) {
+	httpClient := newHostsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host + r.URL.Path {
+		case "plc.test/did:plc:ewvi7nxzyoun6zhxrhs64oiz":
+			_, _ = w.Write([]byte(plcDocument))
+		case "plc.test/did:plc:tombstoned":
+			w.WriteHeader(http.StatusGone)
+		case "plc.test/did:plc:broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "plc.test/did:plc:other":
+			_, _ = w.Write([]byte(`{"id":"did:plc:someone"}`))
+		case "example.com:8443/.well-known/did.json":
+			_, _ = w.Write([]byte(`{"id":"did:web:example.com%3A8443","alsoKnownAs":["at://example.com"]}`))
+		case "example.com/.well-known/did.json":
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	resolver := NewDIDResolver(WithPLCURL("https://plc.test/"), WithDIDHTTPClient(httpClient))
+
+	type out struct {
+		doc    *bsky.DIDDoc
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a did:plc, When it is resolved, Then it should return its document from the PLC directory",
+			in:   "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+			out: out{doc: &bsky.DIDDoc{
+				Context:     []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/multikey/v1"},
+				ID:          "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+				AlsoKnownAs: []string{"at://atproto.com"},
+				VerificationMethod: []bsky.DIDVerificationMethod{{
+					ID:                 "did:plc:ewvi7nxzyoun6zhxrhs64oiz#atproto",
+					Type:               "Multikey",
+					Controller:         "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+					PublicKeyMultibase: "zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w",
+				}},
+				Service: []bsky.DIDService{{
+					ID:              "#atproto_pds",
+					Type:            "AtprotoPersonalDataServer",
+					ServiceEndpoint: "https://enoki.us-east.host.bsky.network",
+				}},
+			}},
+		},
+		{
+			name: "Given a did:web with a port, When it is resolved, Then it should return its well-known document",
+			in:   "did:web:example.com%3A8443",
+			out:  out{doc: &bsky.DIDDoc{ID: "did:web:example.com%3A8443", AlsoKnownAs: []string{"at://example.com"}}},
+		},
+		{
+			name: "Given an unknown did:plc, When it is resolved, Then it should return ErrDIDNotFound",
+			in:   "did:plc:unknown",
+			out:  out{err: ErrDIDNotFound, errMsg: "identity: did not found: did:plc:unknown"},
+		},
+		{
+			name: "Given a tombstoned did:plc, When it is resolved, Then it should return ErrDIDNotFound",
+			in:   "did:plc:tombstoned",
+			out:  out{err: ErrDIDNotFound, errMsg: "identity: did not found: did:plc:tombstoned"},
+		},
+		{
+			name: "Given a failing PLC directory, When a did:plc is resolved, Then it should return an error",
+			in:   "did:plc:broken",
+			out:  out{errMsg: "identity: fail to resolve did:plc:broken: unexpected status 500"},
+		},
+		{
+			name: "Given a document of another DID, When it is resolved, Then it should return an error",
+			in:   "did:plc:other",
+			out:  out{errMsg: "identity: document of did:plc:other is for did:plc:someone"},
+		},
+		{
+			name: "Given a did:web serving an invalid document, When it is resolved, Then it should return an error",
+			in:   "did:web:example.com",
+			out:  out{errMsg: "identity: fail to decode document of did:web:example.com: invalid character 'o' in literal null (expecting 'u')"},
+		},
+		{
+			name: "Given a did:web with a path, When it is resolved, Then it should return ErrUnsupportedDID",
+			in:   "did:web:example.com:user:alice",
+			out:  out{err: ErrUnsupportedDID, errMsg: `identity: unsupported did method: "did:web:example.com:user:alice"`},
+		},
+		{
+			name: "Given a DID of another method, When it is resolved, Then it should return ErrUnsupportedDID",
+			in:   "did:key:zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w",
+			out:  out{err: ErrUnsupportedDID, errMsg: `identity: unsupported did method: "did:key:zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := resolver.ResolveDID(context.Background(), tt.in)
+			if tt.out.errMsg != "" {
+				if tt.out.err != nil {
+					assert.ErrorIs(t, err, tt.out.err)
+				}
+				assert.EqualError(t, err, tt.out.errMsg)
+				assert.Nil(t, doc)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.doc, doc)
+		})
+	}
+}
+
+func TestNewDIDResolver(t *testing.T) {
+	r := NewDIDResolver()
+
+	assert.Equal(t, DefaultPLCURL, r.plcURL)
+	assert.Equal(t, defaultTimeout, r.httpClient.Timeout)
+}
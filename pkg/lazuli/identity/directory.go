@@ -0,0 +1,273 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+)
+
+// HandleInvalid is the handle given to identities whose declared handle does not resolve back to their DID.
+const HandleInvalid = "handle.invalid"
+
+// ErrHandleMismatch is returned when a handle resolves to a DID whose document does not declare the handle back.
+var ErrHandleMismatch = errors.New("identity: handle does not match the did document")
+
+const (
+	defaultTTL         = time.Hour
+	defaultNegativeTTL = 5 * time.Minute
+	defaultCacheSize   = 50_000
+)
+
+// Identity is a DID along with its document and its verified handle.
+type Identity struct {
+	DID string
+	// Handle is the handle declared by the document once it was checked to resolve back to the DID, or
+	// HandleInvalid otherwise.
+	Handle string
+	Doc    *bsky.DIDDoc
+}
+
+// PDSEndpoint returns the URL of the PDS hosting the account.
+func (i *Identity) PDSEndpoint() string {
+	return i.Doc.PDSEndpoint()
+}
+
+// DirectoryOption changes how a Directory resolves and caches identities.
+type DirectoryOption func(d *Directory)
+
+// WithHandleResolver makes the directory resolve handles with r.
+func WithHandleResolver(r *HandleResolver) DirectoryOption {
+	return func(d *Directory) {
+		d.handles = r
+	}
+}
+
+// WithDIDResolver makes the directory resolve DIDs with r.
+func WithDIDResolver(r *DIDResolver) DirectoryOption {
+	return func(d *Directory) {
+		d.dids = r
+	}
+}
+
+// WithCacheTTL sets how long resolved identities are cached, and how long failed resolutions of handles and DIDs that
+// do not exist, along with identities whose handle could not be checked, are cached. A zero ttl disables the matching
+// cache.
+func WithCacheTTL(ttl, negativeTTL time.Duration) DirectoryOption {
+	return func(d *Directory) {
+		d.ttl = ttl
+		d.negativeTTL = negativeTTL
+	}
+}
+
+// WithCacheSize sets how many identities, DID documents and handles are cached at most, each. Once full, the least recently
+// used entries are dropped. A zero size disables the cache.
+func WithCacheSize(size int) DirectoryOption {
+	return func(d *Directory) {
+		d.cacheSize = size
+	}
+}
+
+// Directory resolves identities from handles or DIDs, verifying that handles and DID documents point to each other,
+// and caches the results. Entries can be invalidated by giving the directory the identity events of the firehose with
+// HandleFirehoseEvent.
+type Directory struct {
+	handles     *HandleResolver
+	dids        *DIDResolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+	cacheSize   int
+	now         func() time.Time
+
+	mu          sync.Mutex
+	identities  *lruCache[*Identity]
+	docs        *lruCache[*bsky.DIDDoc]
+	handleCache *lruCache[string]
+}
+
+// NewDirectory creates an identity directory.
+func NewDirectory(opts ...DirectoryOption) *Directory {
+	d := &Directory{
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+		cacheSize:   defaultCacheSize,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.identities = newLRUCache[*Identity](d.cacheSize)
+	d.docs = newLRUCache[*bsky.DIDDoc](d.cacheSize)
+	d.handleCache = newLRUCache[string](d.cacheSize)
+
+	if d.handles == nil {
+		d.handles = NewHandleResolver()
+	}
+	if d.dids == nil {
+		d.dids = NewDIDResolver()
+	}
+
+	return d
+}
+
+// LookupDID returns the identity of did, with its handle verified. When the handle cannot be checked, such as when its
+// domain does not answer, the identity is returned with HandleInvalid and cached for the negative TTL only.
+func (d *Directory) LookupDID(ctx context.Context, did string) (*Identity, error) {
+	if ident, ok, err := cached(d, d.identities, did); ok {
+		return ident, err
+	}
+
+	doc, err := d.resolveDoc(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+
+	ident := &Identity{DID: did, Handle: HandleInvalid, Doc: doc}
+	ttl := d.ttl
+	if handle := doc.Handle(); handle != "" {
+		resolved, err := d.resolveHandle(ctx, handle)
+		if err != nil && !isPermanent(err) {
+			ttl = d.negativeTTL
+		}
+		if resolved == did {
+			ident.Handle = handle
+		}
+	}
+
+	store(d, d.identities, did, ident, nil, ttl)
+	return ident, nil
+}
+
+// LookupHandle returns the identity of the account using handle. It returns ErrHandleMismatch when the handle
+// resolves to a DID whose document declares another handle.
+func (d *Directory) LookupHandle(ctx context.Context, handle string) (*Identity, error) {
+	handle, err := NormalizeHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	did, err := d.resolveHandle(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	ident, err := d.LookupDID(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if ident.Handle != handle {
+		return nil, fmt.Errorf("%w: %s resolves to %s", ErrHandleMismatch, handle, did)
+	}
+
+	return ident, nil
+}
+
+// ResolveDID returns the DID document of did, using the cache, without verifying its handle. It lets a Directory be
+// used by repo.Verifier.
+func (d *Directory) ResolveDID(ctx context.Context, did string) (*bsky.DIDDoc, error) {
+	return d.resolveDoc(ctx, did)
+}
+
+// Purge removes did from the cache, along with the handles cached for it, so the next lookup resolves it again.
+func (d *Directory) Purge(did string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.identities.remove(did)
+	d.docs.remove(did)
+	d.handleCache.removeFunc(func(entry cacheEntry[string]) bool {
+		return entry.value == did
+	})
+}
+
+// PurgeHandle removes handle from the cache, so the next lookup resolves it again.
+func (d *Directory) PurgeHandle(handle string) {
+	if normalized, err := NormalizeHandle(handle); err == nil {
+		handle = normalized
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handleCache.remove(handle)
+}
+
+// HandleFirehoseEvent invalidates the cached identity of the account of identity, handle and tombstone events, and
+// ignores any other event. It has the signature of lazuli.HandlerCommitFn, so it can be called from the handler given
+// to ConsumeFirehose.
+func (d *Directory) HandleFirehoseEvent(evt bsky.CommitEvent) error {
+	switch e := evt.(type) {
+	case bsky.RepoIdentityEvent:
+		d.Purge(e.DID)
+		if e.Handle != nil {
+			d.PurgeHandle(*e.Handle)
+		}
+	case bsky.RepoHandleEvent:
+		d.Purge(e.DID)
+		d.PurgeHandle(e.Handle)
+	case bsky.RepoTombstoneEvent:
+		d.Purge(e.DID)
+	}
+	return nil
+}
+
+func (d *Directory) resolveHandle(ctx context.Context, handle string) (string, error) {
+	if did, ok, err := cached(d, d.handleCache, handle); ok {
+		return did, err
+	}
+
+	did, err := d.handles.ResolveHandle(ctx, handle)
+	store(d, d.handleCache, handle, did, err, d.cacheTTL(err))
+	return did, err
+}
+
+func (d *Directory) resolveDoc(ctx context.Context, did string) (*bsky.DIDDoc, error) {
+	if doc, ok, err := cached(d, d.docs, did); ok {
+		return doc, err
+	}
+
+	doc, err := d.dids.ResolveDID(ctx, did)
+	store(d, d.docs, did, doc, err, d.cacheTTL(err))
+	return doc, err
+}
+
+// cacheTTL returns how long the result of a resolution failing with err is cached, which is zero for failures that
+// may not happen again.
+func (d *Directory) cacheTTL(err error) time.Duration {
+	switch {
+	case err == nil:
+		return d.ttl
+	case isPermanent(err):
+		return d.negativeTTL
+	default:
+		return 0
+	}
+}
+
+// isPermanent reports whether err means that a handle or DID does not exist, rather than a failure that may not
+// happen again, so it can be cached.
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrHandleNotFound) || errors.Is(err, ErrInvalidHandle) ||
+		errors.Is(err, ErrDIDNotFound) || errors.Is(err, ErrUnsupportedDID)
+}
+
+func cached[T any](d *Directory, cache *lruCache[T], key string) (T, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := cache.get(key, d.now())
+	return entry.value, ok, entry.err
+}
+
+func store[T any](d *Directory, cache *lruCache[T], key string, value T, err error, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cache.put(cacheEntry[T]{key: key, value: value, err: err, expires: d.now().Add(ttl)})
+}
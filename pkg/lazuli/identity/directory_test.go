@@ -0,0 +1,297 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// directoryFixture serves the documents of a few accounts and counts the requests made for each of them.
+type directoryFixture struct {
+	mu       sync.Mutex
+	docs     map[string]string
+	requests map[string]int
+}
+
+func newDirectory(t *testing.T, fixture *directoryFixture, opts ...DirectoryOption) *Directory {
+	fixture.requests = map[string]int{}
+	httpClient := newHostsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fixture.mu.Lock()
+		defer fixture.mu.Unlock()
+
+		did := r.URL.Path[1:]
+		fixture.requests[did]++
+		doc, ok := fixture.docs[did]
+		switch {
+		case did == "did:plc:broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte(doc))
+		}
+	})
+
+	dns := txtResolverMock{
+		"_atproto.alice.test":   {"did=did:plc:alice"},
+		"_atproto.mallory.test": {"did=did:plc:mallory"},
+		"_atproto.bob.test":     {"did=did:plc:alice"},
+		"_atproto.flaky.test":   nil,
+	}
+
+	opts = append([]DirectoryOption{
+		WithHandleResolver(NewHandleResolver(WithTXTResolver(dns), WithHandleHTTPClient(httpClient))),
+		WithDIDResolver(NewDIDResolver(WithPLCURL("https://plc.test"), WithDIDHTTPClient(httpClient))),
+	}, opts...)
+	return NewDirectory(opts...)
+}
+
+func docJSON(did, handle string) string {
+	return fmt.Sprintf(`{"id":%q,"alsoKnownAs":["at://%s"]}`, did, handle)
+}
+
+func TestDirectory_LookupDID(t *testing.T) {
+	fixture := &directoryFixture{docs: map[string]string{
+		"did:plc:alice":   docJSON("did:plc:alice", "alice.test"),
+		"did:plc:mallory": docJSON("did:plc:mallory", "alice.test"),
+		"did:plc:nobody":  docJSON("did:plc:nobody", "nobody.test"),
+	}}
+
+	type out struct {
+		handle string
+		err    error
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a DID whose handle resolves back to it, When it is looked up, Then it should return the verified handle",
+			in:   "did:plc:alice",
+			out:  out{handle: "alice.test"},
+		},
+		{
+			name: "Given a DID declaring the handle of another account, When it is looked up, Then its handle should be invalid",
+			in:   "did:plc:mallory",
+			out:  out{handle: HandleInvalid},
+		},
+		{
+			name: "Given a DID declaring a handle that does not resolve, When it is looked up, Then its handle should be invalid",
+			in:   "did:plc:nobody",
+			out:  out{handle: HandleInvalid},
+		},
+		{
+			name: "Given an unknown DID, When it is looked up, Then it should return ErrDIDNotFound",
+			in:   "did:plc:unknown",
+			out:  out{err: ErrDIDNotFound},
+		},
+	}
+
+	d := newDirectory(t, fixture)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ident, err := d.LookupDID(context.Background(), tt.in)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				assert.Nil(t, ident)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.in, ident.DID)
+			assert.Equal(t, tt.out.handle, ident.Handle)
+			assert.Equal(t, tt.in, ident.Doc.ID)
+			assert.Empty(t, ident.PDSEndpoint())
+		})
+	}
+}
+
+func TestDirectory_LookupHandle(t *testing.T) {
+	fixture := &directoryFixture{docs: map[string]string{
+		"did:plc:alice": docJSON("did:plc:alice", "alice.test"),
+	}}
+
+	type out struct {
+		did    string
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a handle declared by its DID, When it is looked up, Then it should return the identity",
+			in:   "@Alice.test",
+			out:  out{did: "did:plc:alice"},
+		},
+		{
+			name: "Given a handle pointing to a DID that declares another handle, When it is looked up, Then it should return ErrHandleMismatch",
+			in:   "bob.test",
+			out:  out{err: ErrHandleMismatch, errMsg: "identity: handle does not match the did document: bob.test resolves to did:plc:alice"},
+		},
+		{
+			name: "Given a handle pointing to an unknown DID, When it is looked up, Then it should return ErrDIDNotFound",
+			in:   "mallory.test",
+			out:  out{err: ErrDIDNotFound, errMsg: "identity: did not found: did:plc:mallory"},
+		},
+		{
+			name: "Given an invalid handle, When it is looked up, Then it should return ErrInvalidHandle",
+			in:   "invalid",
+			out:  out{err: ErrInvalidHandle, errMsg: `identity: invalid handle: "invalid"`},
+		},
+	}
+
+	d := newDirectory(t, fixture)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ident, err := d.LookupHandle(context.Background(), tt.in)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				assert.EqualError(t, err, tt.out.errMsg)
+				assert.Nil(t, ident)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.did, ident.DID)
+		})
+	}
+}
+
+func TestDirectory_cache(t *testing.T) {
+	now := time.Now()
+	fixture := &directoryFixture{docs: map[string]string{
+		"did:plc:alice": docJSON("did:plc:alice", "alice.test"),
+		"did:plc:flaky": docJSON("did:plc:flaky", "flaky.test"),
+	}}
+	d := newDirectory(t, fixture, WithCacheTTL(time.Hour, time.Minute))
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	t.Run("Given a resolved identity, When it is looked up again, Then it should be served from the cache", func(t *testing.T) {
+		_, err := d.LookupDID(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		doc, err := d.ResolveDID(ctx, "did:plc:alice")
+		require.NoError(t, err)
+
+		assert.Equal(t, "did:plc:alice", doc.ID)
+		assert.Equal(t, 1, fixture.requests["did:plc:alice"])
+	})
+
+	t.Run("Given a DID that does not exist, When it is looked up again, Then the failure should be served from the cache", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := d.LookupDID(ctx, "did:plc:unknown")
+			assert.ErrorIs(t, err, ErrDIDNotFound)
+		}
+		assert.Equal(t, 1, fixture.requests["did:plc:unknown"])
+	})
+
+	t.Run("Given a failing resolution, When it is looked up again, Then it should be resolved again", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := d.LookupDID(ctx, "did:plc:broken")
+			assert.EqualError(t, err, "identity: fail to resolve did:plc:broken: unexpected status 502")
+		}
+		assert.Equal(t, 2, fixture.requests["did:plc:broken"])
+	})
+
+	t.Run("Given a handle whose DNS lookup times out, When its DID is looked up, Then its handle should be invalid for the negative TTL only", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			ident, err := d.LookupDID(ctx, "did:plc:flaky")
+			require.NoError(t, err)
+			assert.Equal(t, HandleInvalid, ident.Handle)
+		}
+		assert.Equal(t, 1, fixture.requests["did:plc:flaky"])
+		_, ok := d.identities.get("did:plc:flaky", now.Add(2*time.Minute))
+		assert.False(t, ok)
+
+		_, err := d.LookupHandle(ctx, "flaky.test")
+		assert.NotErrorIs(t, err, ErrHandleNotFound)
+		assert.Error(t, err)
+	})
+
+	t.Run("Given a handle whose DNS lookup times out, When its DID document is resolved, Then it should not depend on the handle", func(t *testing.T) {
+		flakyFixture := &directoryFixture{docs: map[string]string{
+			"did:plc:flaky": docJSON("did:plc:flaky", "flaky.test"),
+		}}
+		flaky := newDirectory(t, flakyFixture)
+		for i := 0; i < 2; i++ {
+			doc, err := flaky.ResolveDID(ctx, "did:plc:flaky")
+			require.NoError(t, err)
+			assert.Equal(t, "did:plc:flaky", doc.ID)
+		}
+		assert.Equal(t, 1, flakyFixture.requests["did:plc:flaky"])
+		assert.Zero(t, flaky.identities.len())
+	})
+
+	t.Run("Given a cache of one entry, When two DIDs are looked up in turn, Then each should be resolved again", func(t *testing.T) {
+		smallFixture := &directoryFixture{}
+		small := newDirectory(t, smallFixture, WithCacheSize(1))
+		for i := 0; i < 2; i++ {
+			_, err := small.LookupDID(ctx, "did:plc:unknown")
+			assert.ErrorIs(t, err, ErrDIDNotFound)
+			_, err = small.LookupDID(ctx, "did:plc:missing")
+			assert.ErrorIs(t, err, ErrDIDNotFound)
+		}
+		assert.Equal(t, 2, smallFixture.requests["did:plc:unknown"])
+		assert.Equal(t, 1, small.docs.len())
+	})
+
+	t.Run("Given expired entries, When they are looked up, Then they should be resolved again", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		_, err := d.LookupDID(ctx, "did:plc:unknown")
+		assert.ErrorIs(t, err, ErrDIDNotFound)
+		_, err = d.LookupDID(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		assert.Equal(t, 2, fixture.requests["did:plc:unknown"])
+		assert.Equal(t, 1, fixture.requests["did:plc:alice"])
+
+		now = now.Add(2 * time.Hour)
+		_, err = d.LookupDID(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		assert.Equal(t, 2, fixture.requests["did:plc:alice"])
+	})
+
+	t.Run("Given identity events from the firehose, When the accounts are looked up, Then they should be resolved again", func(t *testing.T) {
+		handle := "alice.test"
+		events := []bsky.CommitEvent{
+			bsky.RepoIdentityEvent{DID: "did:plc:alice", Handle: &handle},
+			bsky.RepoHandleEvent{DID: "did:plc:alice", Handle: "alice.test"},
+			bsky.RepoTombstoneEvent{DID: "did:plc:alice"},
+		}
+
+		for i, evt := range events {
+			require.NoError(t, d.HandleFirehoseEvent(evt))
+			ident, err := d.LookupHandle(ctx, "alice.test")
+			require.NoError(t, err)
+			assert.Equal(t, "alice.test", ident.Handle)
+			assert.Equal(t, 3+i, fixture.requests["did:plc:alice"])
+		}
+
+		require.NoError(t, d.HandleFirehoseEvent(bsky.RepoCommitEvent{Repo: "did:plc:alice"}))
+		_, err := d.LookupDID(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		assert.Equal(t, 5, fixture.requests["did:plc:alice"])
+	})
+}
+
+func TestNewDirectory(t *testing.T) {
+	d := NewDirectory()
+
+	assert.Equal(t, defaultTTL, d.ttl)
+	assert.Equal(t, defaultNegativeTTL, d.negativeTTL)
+	assert.Equal(t, defaultCacheSize, d.cacheSize)
+	assert.NotNil(t, d.handles)
+	assert.NotNil(t, d.dids)
+}
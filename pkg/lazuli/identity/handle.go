@@ -0,0 +1,259 @@
+// Package identity resolves AT Protocol identities: handles to DIDs, and DIDs to their DID documents.
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidHandle is returned for strings that are not valid handles.
+	ErrInvalidHandle = errors.New("identity: invalid handle")
+	// ErrHandleNotFound is returned when no resolution method finds the DID of a handle.
+	ErrHandleNotFound = errors.New("identity: handle not found")
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// maxWellKnownSize limits the body read from the well-known endpoint, which only holds a DID.
+	maxWellKnownSize = 2048
+)
+
+// handleRegexp matches the handle syntax of the AT Protocol, which is a domain name of at least two labels.
+var handleRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// TXTResolver looks up the DNS TXT records of a name. It is implemented by *net.Resolver.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// HandleResolverOption changes how a HandleResolver resolves handles.
+type HandleResolverOption func(r *HandleResolver)
+
+// WithTXTResolver makes the resolver look up DNS records with dns instead of net.DefaultResolver.
+func WithTXTResolver(dns TXTResolver) HandleResolverOption {
+	return func(r *HandleResolver) {
+		r.dns = dns
+	}
+}
+
+// WithHandleHTTPClient makes the resolver send its HTTP requests with httpClient.
+func WithHandleHTTPClient(httpClient *http.Client) HandleResolverOption {
+	return func(r *HandleResolver) {
+		r.httpClient = httpClient
+	}
+}
+
+// WithResolveHandleFallback makes the resolver ask the server at xrpcURL with com.atproto.identity.resolveHandle when
+// neither DNS nor HTTPS resolve a handle, such as "https://public.api.bsky.app/xrpc".
+func WithResolveHandleFallback(xrpcURL string) HandleResolverOption {
+	return func(r *HandleResolver) {
+		r.xrpcURL = xrpcURL
+	}
+}
+
+// HandleResolver resolves handles to DIDs with the _atproto DNS TXT record of the handle, then with its
+// /.well-known/atproto-did HTTPS endpoint, and finally with an optional com.atproto.identity.resolveHandle fallback.
+type HandleResolver struct {
+	dns        TXTResolver
+	httpClient *http.Client
+	xrpcURL    string
+}
+
+// NewHandleResolver creates a handle resolver.
+func NewHandleResolver(opts ...HandleResolverOption) *HandleResolver {
+	r := &HandleResolver{}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.dns == nil {
+		r.dns = net.DefaultResolver
+	}
+	if r.httpClient == nil {
+		r.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return r
+}
+
+// NormalizeHandle returns the lowercase form of handle, or ErrInvalidHandle when it is not a valid handle.
+func NormalizeHandle(handle string) (string, error) {
+	handle = strings.ToLower(strings.TrimPrefix(handle, "@"))
+	if len(handle) > 253 || !handleRegexp.MatchString(handle) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidHandle, handle)
+	}
+	return handle, nil
+}
+
+// ResolveHandle returns the DID of handle. It returns ErrHandleNotFound, wrapping the error of every method tried,
+// when each of them answered that the handle has no DID, such as a NXDOMAIN or a 404. When a method could not answer,
+// such as with a DNS timeout or a server error, it returns another error, since the handle may resolve once the
+// failure is gone.
+func (r *HandleResolver) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	handle, err := NormalizeHandle(handle)
+	if err != nil {
+		return "", err
+	}
+
+	did, dnsErr := r.resolveDNS(ctx, handle)
+	if dnsErr == nil {
+		return did, nil
+	}
+	did, httpErr := r.resolveWellKnown(ctx, handle)
+	if httpErr == nil {
+		return did, nil
+	}
+	errs := []error{dnsErr, httpErr}
+
+	if r.xrpcURL != "" {
+		did, xrpcErr := r.resolveXRPC(ctx, handle)
+		if xrpcErr == nil {
+			return did, nil
+		}
+		errs = append(errs, xrpcErr)
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", ctxErr
+	}
+	for _, err := range errs {
+		if !isNotFound(err) {
+			return "", fmt.Errorf("identity: fail to resolve %s: %w", handle, errors.Join(errs...))
+		}
+	}
+	return "", fmt.Errorf("%w: %s: %w", ErrHandleNotFound, handle, errors.Join(errs...))
+}
+
+// notFoundError is the failure of a resolution method that answered that the handle has no DID, as opposed to a
+// failure to get an answer.
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.err
+}
+
+func notFound(err error) error {
+	return &notFoundError{err: err}
+}
+
+// isNotFound reports whether err is the answer that a handle has no DID, including names that do not exist in DNS.
+func isNotFound(err error) bool {
+	var notFoundErr *notFoundError
+	if errors.As(err, &notFoundErr) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+func (r *HandleResolver) resolveDNS(ctx context.Context, handle string) (string, error) {
+	records, err := r.dns.LookupTXT(ctx, "_atproto."+handle)
+	if err != nil {
+		return "", fmt.Errorf("dns: %w", err)
+	}
+
+	var did string
+	for _, record := range records {
+		value, ok := strings.CutPrefix(strings.TrimSpace(record), "did=")
+		if !ok {
+			continue
+		}
+		if did != "" && did != value {
+			return "", notFound(errors.New("dns: handle has more than one DID"))
+		}
+		did = value
+	}
+	if !isDID(did) {
+		return "", notFound(errors.New("dns: no DID record"))
+	}
+
+	return did, nil
+}
+
+func (r *HandleResolver) resolveWellKnown(ctx context.Context, handle string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s/.well-known/atproto-did", handle), nil)
+	if err != nil {
+		return "", fmt.Errorf("https: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("https: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return "", notFound(fmt.Errorf("https: unexpected status %d", resp.StatusCode))
+	default:
+		return "", fmt.Errorf("https: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWellKnownSize))
+	if err != nil {
+		return "", fmt.Errorf("https: %w", err)
+	}
+	did := strings.TrimSpace(string(body))
+	if !isDID(did) {
+		return "", notFound(errors.New("https: response is not a DID"))
+	}
+
+	return did, nil
+}
+
+func (r *HandleResolver) resolveXRPC(ctx context.Context, handle string) (string, error) {
+	reqURL := fmt.Sprintf("%s/com.atproto.identity.resolveHandle?%s", r.xrpcURL, url.Values{"handle": {handle}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("xrpc: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("xrpc: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound:
+		// resolveHandle answers unknown handles with a 400
+		return "", notFound(fmt.Errorf("xrpc: unexpected status %d", resp.StatusCode))
+	default:
+		return "", fmt.Errorf("xrpc: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		DID string `json:"did"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("xrpc: %w", err)
+	}
+	if !isDID(body.DID) {
+		return "", notFound(errors.New("xrpc: response is not a DID"))
+	}
+
+	return body.DID, nil
+}
+
+func isDID(s string) bool {
+	method, id, ok := strings.Cut(strings.TrimPrefix(s, "did:"), ":")
+	return ok && strings.HasPrefix(s, "did:") && method != "" && id != ""
+}
@@ -0,0 +1,182 @@
+package identity
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txtResolverMock answers with the records of each name, with NXDOMAIN for missing names and with a timeout for names
+// holding nil.
+type txtResolverMock map[string][]string
+
+func (m txtResolverMock) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := m[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	if records == nil {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	return records, nil
+}
+
+// newHostsServer starts a TLS server answering for every host, and returns a client sending every request to it.
+func newHostsServer(t *testing.T, handler http.HandlerFunc) *http.Client {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	httpClient := server.Client()
+	transport := httpClient.Transport.(*http.Transport)
+	transport.TLSClientConfig.ServerName = "example.com"
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return httpClient
+}
+
+func TestHandleResolver_ResolveHandle(t *testing.T) {
+	dns := txtResolverMock{
+		"_atproto.dns.test":       {"some other record", "did=did:plc:dns"},
+		"_atproto.ambiguous.test": {"did=did:plc:first", "did=did:plc:second"},
+		"_atproto.invalid.test":   {"did=not-a-did"},
+		"_atproto.timeout.test":   nil,
+	}
+	httpClient := newHostsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/xrpc/com.atproto.identity.resolveHandle" && r.URL.Query().Get("handle") == "fallback.test":
+			_, _ = w.Write([]byte(`{"did":"did:plc:fallback"}`))
+		case r.URL.Path != "/.well-known/atproto-did":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Host == "https.test":
+			_, _ = w.Write([]byte("did:web:https.test\n"))
+		case r.Host == "html.test":
+			_, _ = w.Write([]byte("<html></html>"))
+		case r.Host == "unavailable.test":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	type in struct {
+		handle string
+		opts   []HandleResolverOption
+	}
+
+	type out struct {
+		did    string
+		err    error
+		errMsg string
+	}
+
+	opts := []HandleResolverOption{WithTXTResolver(dns), WithHandleHTTPClient(httpClient)}
+	withFallback := append(append([]HandleResolverOption{}, opts...), WithResolveHandleFallback("https://api.test/xrpc"))
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given a handle with a DNS TXT record, When it is resolved, Then it should return the DID of the record",
+			in:   in{handle: "@DNS.test", opts: opts},
+			out:  out{did: "did:plc:dns"},
+		},
+		{
+			name: "Given a handle with a well-known endpoint, When it is resolved, Then it should return the DID served by it",
+			in:   in{handle: "https.test", opts: opts},
+			out:  out{did: "did:web:https.test"},
+		},
+		{
+			name: "Given a handle only known by the XRPC fallback, When it is resolved, Then it should return the DID of the fallback",
+			in:   in{handle: "fallback.test", opts: withFallback},
+			out:  out{did: "did:plc:fallback"},
+		},
+		{
+			name: "Given a handle with more than one DID record and no well-known endpoint, When it is resolved, Then it should return ErrHandleNotFound",
+			in:   in{handle: "ambiguous.test", opts: opts},
+			out: out{
+				err:    ErrHandleNotFound,
+				errMsg: "identity: handle not found: ambiguous.test: dns: handle has more than one DID\nhttps: unexpected status 404",
+			},
+		},
+		{
+			name: "Given a handle with invalid DID records and responses, When it is resolved, Then it should return ErrHandleNotFound",
+			in:   in{handle: "html.test", opts: withFallback},
+			out: out{
+				err: ErrHandleNotFound,
+				errMsg: "identity: handle not found: html.test: dns: lookup _atproto.html.test: no such host\n" +
+					"https: response is not a DID\nxrpc: unexpected status 404",
+			},
+		},
+		{
+			name: "Given a DNS record that is not a DID, When it is resolved, Then it should try the other methods",
+			in:   in{handle: "invalid.test", opts: opts},
+			out: out{
+				err:    ErrHandleNotFound,
+				errMsg: "identity: handle not found: invalid.test: dns: no DID record\nhttps: unexpected status 404",
+			},
+		},
+		{
+			name: "Given a DNS lookup timing out, When it is resolved, Then it should not return ErrHandleNotFound",
+			in:   in{handle: "timeout.test", opts: opts},
+			out: out{
+				errMsg: "identity: fail to resolve timeout.test: dns: lookup _atproto.timeout.test: i/o timeout\nhttps: unexpected status 404",
+			},
+		},
+		{
+			name: "Given a well-known endpoint failing, When it is resolved, Then it should not return ErrHandleNotFound",
+			in:   in{handle: "unavailable.test", opts: opts},
+			out: out{
+				errMsg: "identity: fail to resolve unavailable.test: dns: lookup _atproto.unavailable.test: no such host\nhttps: unexpected status 503",
+			},
+		},
+		{
+			name: "Given an invalid handle, When it is resolved, Then it should return ErrInvalidHandle",
+			in:   in{handle: "not a handle", opts: opts},
+			out:  out{err: ErrInvalidHandle, errMsg: `identity: invalid handle: "not a handle"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			did, err := NewHandleResolver(tt.in.opts...).ResolveHandle(context.Background(), tt.in.handle)
+			if tt.out.errMsg != "" {
+				if tt.out.err != nil {
+					assert.ErrorIs(t, err, tt.out.err)
+				} else {
+					assert.NotErrorIs(t, err, ErrHandleNotFound)
+				}
+				assert.EqualError(t, err, tt.out.errMsg)
+				assert.Empty(t, did)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.did, did)
+		})
+	}
+
+	t.Run("Given a canceled context, When a handle is resolved, Then it should return the context error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewHandleResolver(withFallback...).ResolveHandle(ctx, "nowhere.test")
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestNewHandleResolver(t *testing.T) {
+	r := NewHandleResolver()
+
+	assert.Equal(t, net.DefaultResolver, r.dns)
+	assert.Equal(t, defaultTimeout, r.httpClient.Timeout)
+	assert.Empty(t, r.xrpcURL)
+}
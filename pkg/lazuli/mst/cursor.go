@@ -0,0 +1,102 @@
+package mst
+
+import (
+	"crypto/sha256"
+	"math/bits"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+)
+
+// unknownLayer is the layer of subtrees whose layer cannot be told before loading them, such as the root of a tree.
+const unknownLayer = -1
+
+// item is either a key of the tree and its value, or a subtree not loaded yet holding the keys found between the
+// neighbouring items.
+type item struct {
+	key     string
+	value   cid.CID
+	subtree cid.CID
+	layer   int
+}
+
+func (i *item) isTree() bool {
+	return i.subtree.Defined()
+}
+
+// cursor goes through the items of a tree in key order, loading subtrees only when they are expanded.
+type cursor struct {
+	tree  *Tree
+	stack [][]item
+}
+
+func newCursor(t *Tree) *cursor {
+	c := &cursor{tree: t}
+	if t.root.Defined() {
+		c.stack = [][]item{{{subtree: t.root, layer: unknownLayer}}}
+	}
+	return c
+}
+
+// next returns the current item, or nil when every item was consumed.
+func (c *cursor) next() (*item, error) {
+	for len(c.stack) > 0 {
+		top := c.stack[len(c.stack)-1]
+		if len(top) > 0 {
+			return &top[0], nil
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return nil, nil
+}
+
+// skip consumes the current item.
+func (c *cursor) skip() {
+	top := c.stack[len(c.stack)-1]
+	c.stack[len(c.stack)-1] = top[1:]
+}
+
+// expand replaces the current item, which must be a subtree, by the items of its root node.
+func (c *cursor) expand() error {
+	it, _ := c.next()
+	n, err := c.tree.node(it.subtree)
+	if err != nil {
+		return err
+	}
+	c.skip()
+
+	layer := it.layer
+	if len(n.entries) > 0 {
+		layer = keyLayer(n.entries[0].key)
+	}
+	childLayer := unknownLayer
+	if layer > 0 {
+		childLayer = layer - 1
+	}
+
+	items := make([]item, 0, 2*len(n.entries)+1)
+	if n.left.Defined() {
+		items = append(items, item{subtree: n.left, layer: childLayer})
+	}
+	for _, e := range n.entries {
+		items = append(items, item{key: e.key, value: e.value, layer: layer})
+		if e.right.Defined() {
+			items = append(items, item{subtree: e.right, layer: childLayer})
+		}
+	}
+	c.stack = append(c.stack, items)
+	return nil
+}
+
+// keyLayer returns the layer of the tree holding key, which is the number of leading zero bits of its SHA-256 hash
+// divided by two, giving the tree a fanout of 4.
+func keyLayer(key string) int {
+	hash := sha256.Sum256([]byte(key))
+	zeros := 0
+	for _, b := range hash {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros / 2
+}
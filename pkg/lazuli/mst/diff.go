@@ -0,0 +1,72 @@
+package mst
+
+import "github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+
+// ChangeAction is the kind of change made to a key between two trees. The values match the actions of firehose
+// operations.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// Change is a key created, updated or deleted between two trees.
+type Change struct {
+	Action ChangeAction
+	Key    string
+	Old    cid.CID // undefined for creations
+	New    cid.CID // undefined for deletions
+}
+
+// expandFirst reports whether the subtree a must be expanded before the subtree b. The higher subtree is expanded
+// first, so both cursors reach the subtrees of the same layer together and the shared ones can be skipped.
+func expandFirst(a, b *item) bool {
+	return a.layer == unknownLayer || (b.layer != unknownLayer && a.layer >= b.layer)
+}
+
+// Diff returns the changes turning the tree from into the tree to, in key order. Subtrees shared by both trees are
+// skipped without being loaded, so only the nodes on the changed paths need to be available.
+func Diff(from, to *Tree) ([]Change, error) {
+	var changes []Change
+	a, b := newCursor(from), newCursor(to)
+
+	for {
+		ia, err := a.next()
+		if err != nil {
+			return nil, err
+		}
+		ib, err := b.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case ia == nil && ib == nil:
+			return changes, nil
+		case ia != nil && ib != nil && ia.isTree() && ib.isTree() && ia.subtree == ib.subtree:
+			a.skip()
+			b.skip()
+		case ia != nil && ia.isTree() && (ib == nil || !ib.isTree() || expandFirst(ia, ib)):
+			err = a.expand()
+		case ib != nil && ib.isTree():
+			err = b.expand()
+		case ib == nil || (ia != nil && ia.key < ib.key):
+			changes = append(changes, Change{Action: ChangeDelete, Key: ia.key, Old: ia.value})
+			a.skip()
+		case ia == nil || ib.key < ia.key:
+			changes = append(changes, Change{Action: ChangeCreate, Key: ib.key, New: ib.value})
+			b.skip()
+		default:
+			if ia.value != ib.value {
+				changes = append(changes, Change{Action: ChangeUpdate, Key: ia.key, Old: ia.value, New: ib.value})
+			}
+			a.skip()
+			b.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package mst reads the Merkle Search Trees (MSTs) holding the records of AT Protocol repositories, where keys are
+// record paths ("collection/rkey") and values are record CIDs.
+package mst
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+)
+
+var (
+	// ErrNotFound is returned when a key is not in the tree.
+	ErrNotFound = errors.New("mst: key not found")
+	// ErrMissingBlock is returned when a node needed to walk the tree is not available, such as a node left out of
+	// the partial tree sent with a firehose commit.
+	ErrMissingBlock = errors.New("mst: missing block")
+)
+
+// BlockGetter returns blocks by CID. It is implemented by car.Archive.
+type BlockGetter interface {
+	Get(c cid.CID) ([]byte, bool)
+}
+
+// Tree is a read-only view of the tree rooted at a node, loading nodes from its blocks as they are needed.
+type Tree struct {
+	blocks BlockGetter
+	root   cid.CID
+}
+
+// Load returns the tree rooted at the node identified by root. An undefined root is an empty tree.
+func Load(blocks BlockGetter, root cid.CID) *Tree {
+	return &Tree{blocks: blocks, root: root}
+}
+
+// LoadCommit returns the tree of the repository commit identified by commit, such as the commit of a firehose event.
+func LoadCommit(blocks BlockGetter, commit cid.CID) (*Tree, error) {
+	data, ok := blocks.Get(commit)
+	if !ok {
+		return nil, fmt.Errorf("%w: commit %s", ErrMissingBlock, commit)
+	}
+
+	var c struct {
+		Data cid.CID `cbor:"data"`
+	}
+	if err := cbor.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("mst: fail to decode commit: %w", err)
+	}
+	if !c.Data.Defined() {
+		return nil, errors.New("mst: commit has no data")
+	}
+
+	return Load(blocks, c.Data), nil
+}
+
+// ReadCAR reads a CAR file whose root is a repository commit, such as the export of com.atproto.sync.getRepo or the
+// blocks of a firehose commit, returning the tree of the commit.
+func ReadCAR(r io.Reader) (*Tree, error) {
+	archive, err := car.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(archive.Roots) == 0 {
+		return nil, errors.New("mst: car file has no root")
+	}
+	return LoadCommit(archive, archive.Roots[0])
+}
+
+// Root returns the CID of the root node of the tree.
+func (t *Tree) Root() cid.CID {
+	return t.root
+}
+
+// Get returns the value stored under key, or ErrNotFound when the tree does not hold the key.
+func (t *Tree) Get(key string) (cid.CID, error) {
+	next := t.root
+	for next.Defined() {
+		n, err := t.node(next)
+		if err != nil {
+			return cid.CID{}, err
+		}
+
+		next = n.left
+		for _, e := range n.entries {
+			if key < e.key {
+				break
+			}
+			if key == e.key {
+				return e.value, nil
+			}
+			next = e.right
+		}
+	}
+
+	return cid.CID{}, ErrNotFound
+}
+
+// Walk calls fn for every key of the tree and its value, in key order. It stops at the first error returned by fn
+// and returns it.
+func (t *Tree) Walk(fn func(key string, value cid.CID) error) error {
+	cur := newCursor(t)
+	for {
+		it, err := cur.next()
+		if err != nil || it == nil {
+			return err
+		}
+		if it.isTree() {
+			if err := cur.expand(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cur.skip()
+		if err := fn(it.key, it.value); err != nil {
+			return err
+		}
+	}
+}
+
+// nodeData is the DAG-CBOR form of a tree node. Keys are compressed by storing only the suffix that differs from
+// the key of the previous entry.
+type nodeData struct {
+	Left    cid.CID     `cbor:"l"`
+	Entries []entryData `cbor:"e"`
+}
+
+type entryData struct {
+	PrefixLen int     `cbor:"p"`
+	KeySuffix []byte  `cbor:"k"`
+	Value     cid.CID `cbor:"v"`
+	Right     cid.CID `cbor:"t"`
+}
+
+// node is a decoded tree node with full keys. The subtree on the left of the first entry is left, and the subtree
+// following each entry is its right.
+type node struct {
+	left    cid.CID
+	entries []entry
+}
+
+type entry struct {
+	key   string
+	value cid.CID
+	right cid.CID
+}
+
+func (t *Tree) node(c cid.CID) (*node, error) {
+	data, ok := t.blocks.Get(c)
+	if !ok {
+		return nil, fmt.Errorf("%w: node %s", ErrMissingBlock, c)
+	}
+	return decodeNode(data)
+}
+
+func decodeNode(data []byte) (*node, error) {
+	var raw nodeData
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("mst: fail to decode node: %w", err)
+	}
+
+	n := &node{left: raw.Left, entries: make([]entry, 0, len(raw.Entries))}
+	var prev []byte
+	for _, e := range raw.Entries {
+		if e.PrefixLen < 0 || e.PrefixLen > len(prev) {
+			return nil, fmt.Errorf("mst: invalid key prefix length %d", e.PrefixLen)
+		}
+
+		key := append(bytes.Clone(prev[:e.PrefixLen]), e.KeySuffix...)
+		if len(n.entries) > 0 && string(key) <= n.entries[len(n.entries)-1].key {
+			return nil, fmt.Errorf("mst: keys of node are not sorted at %q", key)
+		}
+
+		n.entries = append(n.entries, entry{key: string(key), value: e.Value, right: e.Right})
+		prev = key
+	}
+
+	return n, nil
+}
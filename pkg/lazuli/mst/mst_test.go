@@ -0,0 +1,448 @@
+package mst
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockMap map[cid.CID][]byte
+
+func (b blockMap) Get(c cid.CID) ([]byte, bool) {
+	data, ok := b[c]
+	return data, ok
+}
+
+// putNode encodes a node with the given left subtree and entries, compressing their keys, and stores it in blocks.
+func putNode(t *testing.T, blocks blockMap, left cid.CID, entries ...entry) cid.CID {
+	raw := nodeData{Left: left, Entries: []entryData{}}
+	prev := ""
+	for _, e := range entries {
+		prefix := 0
+		for prefix < len(prev) && prefix < len(e.key) && prev[prefix] == e.key[prefix] {
+			prefix++
+		}
+		raw.Entries = append(raw.Entries, entryData{
+			PrefixLen: prefix,
+			KeySuffix: []byte(e.key[prefix:]),
+			Value:     e.value,
+			Right:     e.right,
+		})
+		prev = e.key
+	}
+
+	data, err := cid.DagCBOR.Marshal(raw)
+	require.NoError(t, err)
+
+	c := cid.Sum(cid.CodecDagCBOR, data)
+	blocks[c] = data
+	return c
+}
+
+func value(s string) cid.CID {
+	return cid.Sum(cid.CodecDagCBOR, []byte(s))
+}
+
+func TestTreeGet(t *testing.T) {
+	blocks := blockMap{}
+	left := putNode(t, blocks, cid.CID{},
+		entry{key: "app.bsky.feed.post/1", value: value("1")},
+		entry{key: "app.bsky.feed.post/2", value: value("2")},
+	)
+	right := putNode(t, blocks, cid.CID{}, entry{key: "app.bsky.feed.post/4", value: value("4")})
+	root := putNode(t, blocks, left, entry{key: "app.bsky.feed.post/3", value: value("3"), right: right})
+
+	partial := blockMap{root: blocks[root], left: blocks[left]}
+
+	invalid := blockMap{}
+	unsorted := putNode(t, invalid, cid.CID{}, entry{key: "b", value: value("b")}, entry{key: "a", value: value("a")})
+	badPrefix := cid.Sum(cid.CodecDagCBOR, []byte("bad prefix"))
+	invalid[badPrefix], _ = cbor.Marshal(nodeData{Entries: []entryData{{PrefixLen: 3, KeySuffix: []byte("a")}}})
+	notNode := cid.Sum(cid.CodecDagCBOR, []byte("not a node"))
+	invalid[notNode] = []byte{0x01}
+
+	type in struct {
+		tree *Tree
+		key  string
+	}
+
+	type out struct {
+		value cid.CID
+		err   error
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given a key in the root node, When Get is called, Then it should return its value",
+			in:   in{tree: Load(blocks, root), key: "app.bsky.feed.post/3"},
+			out:  out{value: value("3")},
+		},
+		{
+			name: "Given a key in the left subtree, When Get is called, Then it should return its value",
+			in:   in{tree: Load(blocks, root), key: "app.bsky.feed.post/2"},
+			out:  out{value: value("2")},
+		},
+		{
+			name: "Given a key in the right subtree, When Get is called, Then it should return its value",
+			in:   in{tree: Load(blocks, root), key: "app.bsky.feed.post/4"},
+			out:  out{value: value("4")},
+		},
+		{
+			name: "Given a key not in the tree, When Get is called, Then it should return ErrNotFound",
+			in:   in{tree: Load(blocks, root), key: "app.bsky.feed.post/0"},
+			out:  out{err: ErrNotFound},
+		},
+		{
+			name: "Given an empty tree, When Get is called, Then it should return ErrNotFound",
+			in:   in{tree: Load(blocks, cid.CID{}), key: "app.bsky.feed.post/1"},
+			out:  out{err: ErrNotFound},
+		},
+		{
+			name: "Given a partial tree without the needed node, When Get is called, Then it should return ErrMissingBlock",
+			in:   in{tree: Load(partial, root), key: "app.bsky.feed.post/5"},
+			out:  out{err: ErrMissingBlock},
+		},
+		{
+			name: "Given a partial tree with the needed nodes, When Get is called, Then it should return the value",
+			in:   in{tree: Load(partial, root), key: "app.bsky.feed.post/1"},
+			out:  out{value: value("1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.tree.Get(tt.in.key)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.value, got)
+		})
+	}
+
+	t.Run("Given malformed nodes, When Get is called, Then it should return a decoding error", func(t *testing.T) {
+		_, err := Load(invalid, unsorted).Get("a")
+		assert.EqualError(t, err, `mst: keys of node are not sorted at "a"`)
+
+		_, err = Load(invalid, badPrefix).Get("a")
+		assert.EqualError(t, err, "mst: invalid key prefix length 3")
+
+		_, err = Load(invalid, notNode).Get("a")
+		assert.ErrorContains(t, err, "mst: fail to decode node")
+
+		assert.Equal(t, unsorted, Load(invalid, unsorted).Root())
+	})
+}
+
+func TestTreeWalk(t *testing.T) {
+	blocks := blockMap{}
+	left := putNode(t, blocks, cid.CID{},
+		entry{key: "app.bsky.feed.like/1", value: value("1")},
+		entry{key: "app.bsky.feed.post/2", value: value("2")},
+	)
+	right := putNode(t, blocks, cid.CID{}, entry{key: "app.bsky.graph.follow/4", value: value("4")})
+	root := putNode(t, blocks, left, entry{key: "app.bsky.feed.post/3", value: value("3"), right: right})
+
+	type out struct {
+		keys []string
+		err  error
+	}
+
+	tests := []struct {
+		name string
+		in   *Tree
+		out  out
+	}{
+		{
+			name: "Given a tree, When it is walked, Then it should visit every key in order",
+			in:   Load(blocks, root),
+			out:  out{keys: []string{"app.bsky.feed.like/1", "app.bsky.feed.post/2", "app.bsky.feed.post/3", "app.bsky.graph.follow/4"}},
+		},
+		{
+			name: "Given an empty tree, When it is walked, Then it should visit no key",
+			in:   Load(blocks, cid.CID{}),
+			out:  out{},
+		},
+		{
+			name: "Given a partial tree, When it is walked, Then it should return ErrMissingBlock",
+			in:   Load(blockMap{root: blocks[root]}, root),
+			out:  out{err: ErrMissingBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			err := tt.in.Walk(func(key string, v cid.CID) error {
+				assert.Equal(t, value(key[len(key)-1:]), v)
+				keys = append(keys, key)
+				return nil
+			})
+
+			assert.ErrorIs(t, err, tt.out.err)
+			assert.Equal(t, tt.out.keys, keys)
+		})
+	}
+
+	t.Run("Given a walk function returning an error, When the tree is walked, Then it should stop with the error", func(t *testing.T) {
+		stop := errors.New("stop")
+		visited := 0
+		err := Load(blocks, root).Walk(func(string, cid.CID) error {
+			visited++
+			return stop
+		})
+
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, visited)
+	})
+}
+
+func TestReadCAR(t *testing.T) {
+	blocks := blockMap{}
+	root := putNode(t, blocks, cid.CID{}, entry{key: "app.bsky.feed.post/1", value: value("1")})
+
+	encodeBlock := func(v any) car.Block {
+		data, err := cbor.Marshal(v)
+		require.NoError(t, err)
+		return car.Block{CID: cid.Sum(cid.CodecDagCBOR, data), Data: data}
+	}
+	commit := encodeBlock(map[string]any{"did": "did:plc:test", "version": 3, "data": root, "rev": "3kabc"})
+	noData := encodeBlock(map[string]any{"did": "did:plc:test", "version": 3})
+	notCommit := encodeBlock("not a commit")
+
+	writeCAR := func(roots []cid.CID, blocks ...car.Block) []byte {
+		var buf bytes.Buffer
+		w, err := car.NewWriter(&buf, roots...)
+		require.NoError(t, err)
+		for _, block := range blocks {
+			require.NoError(t, w.Write(block))
+		}
+		return buf.Bytes()
+	}
+	node := car.Block{CID: root, Data: blocks[root]}
+
+	type out struct {
+		keys []string
+		err  string
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		out  out
+	}{
+		{
+			name: "Given a repository CAR file, When it is read, Then it should return the tree of its commit",
+			in:   writeCAR([]cid.CID{commit.CID}, commit, node),
+			out:  out{keys: []string{"app.bsky.feed.post/1"}},
+		},
+		{
+			name: "Given a CAR file without roots, When it is read, Then it should return an error",
+			in:   writeCAR(nil, node),
+			out:  out{err: "mst: car file has no root"},
+		},
+		{
+			name: "Given a CAR file without its commit block, When it is read, Then it should return an error",
+			in:   writeCAR([]cid.CID{commit.CID}, node),
+			out:  out{err: "mst: missing block: commit " + commit.CID.String()},
+		},
+		{
+			name: "Given a commit without data, When it is read, Then it should return an error",
+			in:   writeCAR([]cid.CID{noData.CID}, noData),
+			out:  out{err: "mst: commit has no data"},
+		},
+		{
+			name: "Given a root that is not a commit, When it is read, Then it should return an error",
+			in:   writeCAR([]cid.CID{notCommit.CID}, notCommit),
+			out:  out{err: "mst: fail to decode commit: cbor: cannot unmarshal UTF-8 text string into Go value of type struct { Data cid.CID \"cbor:\\\"data\\\"\" }"},
+		},
+		{
+			name: "Given a malformed CAR file, When it is read, Then it should return an error",
+			in:   []byte{0x01},
+			out:  out{err: "car: fail to read header: unexpected EOF"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ReadCAR(bytes.NewReader(tt.in))
+			if tt.out.err != "" {
+				assert.EqualError(t, err, tt.out.err)
+				return
+			}
+
+			require.NoError(t, err)
+			var keys []string
+			require.NoError(t, tree.Walk(func(key string, _ cid.CID) error {
+				keys = append(keys, key)
+				return nil
+			}))
+			assert.Equal(t, tt.out.keys, keys)
+		})
+	}
+}
+
+// buildTree stores in blocks the nodes of the tree holding keys, which must be sorted, each with the value
+// value(key). Keys are placed in the layer given by their hash, as a repository would.
+func buildTree(t *testing.T, blocks blockMap, keys []string) cid.CID {
+	layer := 0
+	for _, key := range keys {
+		layer = max(layer, keyLayer(key))
+	}
+	return buildNode(t, blocks, keys, layer)
+}
+
+func buildNode(t *testing.T, blocks blockMap, keys []string, layer int) cid.CID {
+	if len(keys) == 0 {
+		return cid.CID{}
+	}
+
+	var left cid.CID
+	var entries []entry
+	var gap []string
+	attach := func() {
+		subtree := cid.CID{}
+		if len(gap) > 0 {
+			subtree = buildNode(t, blocks, gap, layer-1)
+		}
+		if len(entries) == 0 {
+			left = subtree
+		} else {
+			entries[len(entries)-1].right = subtree
+		}
+		gap = nil
+	}
+
+	for _, key := range keys {
+		if keyLayer(key) < layer {
+			gap = append(gap, key)
+			continue
+		}
+		attach()
+		entries = append(entries, entry{key: key, value: value(key)})
+	}
+	attach()
+
+	return putNode(t, blocks, left, entries...)
+}
+
+func TestDiff(t *testing.T) {
+	keys := make([]string, 0, 64)
+	for i := 0; i < 64; i++ {
+		keys = append(keys, fmt.Sprintf("app.bsky.feed.post/%03d", i))
+	}
+
+	oldBlocks, newBlocks := blockMap{}, blockMap{}
+	oldRoot := buildTree(t, oldBlocks, keys[:63])
+	newRoot := buildTree(t, newBlocks, append(append([]string{}, keys[1:40]...), keys[41:]...))
+
+	// the nodes shared by both trees are left out to check that they are skipped without being loaded
+	partial := blockMap{}
+	for c, data := range oldBlocks {
+		if _, shared := newBlocks[c]; !shared {
+			partial[c] = data
+		}
+	}
+	for c, data := range newBlocks {
+		if _, shared := oldBlocks[c]; !shared {
+			partial[c] = data
+		}
+	}
+	require.Less(t, len(partial), len(oldBlocks)+len(newBlocks))
+
+	small := blockMap{}
+	shared := putNode(t, small, cid.CID{},
+		entry{key: "app.bsky.feed.like/1", value: value("1")},
+		entry{key: "app.bsky.feed.like/2", value: value("2")},
+	)
+	right := putNode(t, small, cid.CID{}, entry{key: "app.bsky.graph.follow/4", value: value("4")})
+	smallRoot := putNode(t, small, shared, entry{key: "app.bsky.feed.post/3", value: value("3"), right: right})
+	changed := putNode(t, small, shared, entry{key: "app.bsky.feed.post/3", value: value("3b"), right: right})
+
+	type in struct {
+		from *Tree
+		to   *Tree
+	}
+
+	type out struct {
+		changes []Change
+		err     error
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given two repository trees, When they are diffed, Then it should return the changes loading only the changed nodes",
+			in:   in{from: Load(partial, oldRoot), to: Load(partial, newRoot)},
+			out: out{changes: []Change{
+				{Action: ChangeDelete, Key: keys[0], Old: value(keys[0])},
+				{Action: ChangeDelete, Key: keys[40], Old: value(keys[40])},
+				{Action: ChangeCreate, Key: keys[63], New: value(keys[63])},
+			}},
+		},
+		{
+			name: "Given a key updated in the root node, When the trees are diffed, Then it should return the update",
+			in:   in{from: Load(blockMap{smallRoot: small[smallRoot]}, smallRoot), to: Load(blockMap{changed: small[changed]}, changed)},
+			out: out{changes: []Change{
+				{Action: ChangeUpdate, Key: "app.bsky.feed.post/3", Old: value("3"), New: value("3b")},
+			}},
+		},
+		{
+			name: "Given an empty tree and a tree, When they are diffed, Then every key should be created",
+			in:   in{from: Load(small, cid.CID{}), to: Load(small, smallRoot)},
+			out: out{changes: []Change{
+				{Action: ChangeCreate, Key: "app.bsky.feed.like/1", New: value("1")},
+				{Action: ChangeCreate, Key: "app.bsky.feed.like/2", New: value("2")},
+				{Action: ChangeCreate, Key: "app.bsky.feed.post/3", New: value("3")},
+				{Action: ChangeCreate, Key: "app.bsky.graph.follow/4", New: value("4")},
+			}},
+		},
+		{
+			name: "Given a tree and an empty tree, When they are diffed, Then every key should be deleted",
+			in:   in{from: Load(small, shared), to: Load(small, cid.CID{})},
+			out: out{changes: []Change{
+				{Action: ChangeDelete, Key: "app.bsky.feed.like/1", Old: value("1")},
+				{Action: ChangeDelete, Key: "app.bsky.feed.like/2", Old: value("2")},
+			}},
+		},
+		{
+			name: "Given the same tree twice, When they are diffed, Then it should return no change",
+			in:   in{from: Load(blockMap{}, oldRoot), to: Load(blockMap{}, oldRoot)},
+			out:  out{},
+		},
+		{
+			name: "Given trees with a changed subtree that is not available, When they are diffed, Then it should return ErrMissingBlock",
+			in:   in{from: Load(blockMap{}, shared), to: Load(small, smallRoot)},
+			out:  out{err: ErrMissingBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.in.from, tt.in.to)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.changes, changes)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package lazuli
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Option configures optional behavior of the client created by NewClient.
+type Option func(c *client)
+
+// WithSessionStore makes the client resume the session saved in store when it is created, and save every session
+// it creates or refreshes afterward. When there is no stored session, CreateSession must be called as usual.
+func WithSessionStore(store SessionStore) Option {
+	return func(c *client) {
+		c.store = store
+	}
+}
+
+// WithIdentityResolver makes CreateSession resolve handles and DIDs with resolver and log in at the PDS hosting the
+// account, so the URL given to NewClient can be left empty. An *identity.Directory can be used as resolver.
+func WithIdentityResolver(resolver IdentityResolver) Option {
+	return func(c *client) {
+		c.identities = resolver
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for XRPC requests, allowing custom transports such as proxies, mTLS or
+// tracing. By default, a client with a timeout of 30 seconds is used.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithDialer sets the websocket dialer used to connect to the firehose.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(c *client) {
+		c.wsDialer = dialer
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, which defaults to "go-lazuli".
+func WithUserAgent(userAgent string) Option {
+	return func(c *client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the timeout of every HTTP request and of the websocket handshake, overriding the one of the HTTP
+// client and dialer given by WithHTTPClient and WithDialer. Only positive values are applied. Repositories downloaded
+// by GetRepo and ForEachRecord are not bound by it, only by their context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHeaders adds headers sent with every request, including the websocket handshake.
+func WithHeaders(headers http.Header) Option {
+	return func(c *client) {
+		if c.headers == nil {
+			c.headers = http.Header{}
+		}
+		for key, values := range headers {
+			for _, value := range values {
+				c.headers.Add(key, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package lazuli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient_options(t *testing.T) {
+	type out struct {
+		timeout          time.Duration
+		handshakeTimeout time.Duration
+		userAgent        string
+	}
+
+	customHTTPClient := &http.Client{Timeout: time.Minute}
+	customDialer := &websocket.Dialer{HandshakeTimeout: time.Minute}
+
+	tests := []struct {
+		name string
+		opts []Option
+		out  out
+	}{
+		{
+			name: "Given no options, When NewClient is called, Then it should use the default timeout and user agent",
+			out: out{
+				timeout:          defaultTimeout,
+				handshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+				userAgent:        defaultUserAgent,
+			},
+		},
+		{
+			name: "Given a custom HTTP client and dialer, When NewClient is called, Then it should use them as they are",
+			opts: []Option{WithHTTPClient(customHTTPClient), WithDialer(customDialer), WithUserAgent("test-agent")},
+			out: out{
+				timeout:          time.Minute,
+				handshakeTimeout: time.Minute,
+				userAgent:        "test-agent",
+			},
+		},
+		{
+			name: "Given a timeout and a custom HTTP client and dialer, When NewClient is called, Then it should apply the timeout to copies of them",
+			opts: []Option{WithTimeout(5 * time.Second), WithHTTPClient(customHTTPClient), WithDialer(customDialer)},
+			out: out{
+				timeout:          5 * time.Second,
+				handshakeTimeout: 5 * time.Second,
+				userAgent:        defaultUserAgent,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("http://localhost", "ws://localhost", tt.opts...).(*client)
+
+			assert.Equal(t, tt.out.timeout, c.httpClient.Timeout)
+			assert.Equal(t, tt.out.handshakeTimeout, c.wsDialer.HandshakeTimeout)
+			assert.Equal(t, tt.out.userAgent, c.userAgent)
+			assert.Equal(t, time.Minute, customHTTPClient.Timeout)
+			assert.Equal(t, time.Minute, customDialer.HandshakeTimeout)
+		})
+	}
+}
+
+func TestNewClient_requestHeaders(t *testing.T) {
+	t.Run("Given a user agent and extra headers, When a request is sent, Then it should carry them", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+			assert.Equal(t, "test-value", r.Header.Get("X-Test"))
+			assert.Equal(t, "did:web:api.bsky.app#bsky_appview", r.Header.Get("Atproto-Proxy"))
+			_ = json.NewEncoder(w).Encode(bsky.AuthResponse{AccessJwt: "valid-token"})
+		}))
+		defer server.Close()
+
+		lazuliClient := NewClient(server.URL, "",
+			WithHTTPClient(server.Client()),
+			WithUserAgent("test-agent"),
+			WithHeaders(http.Header{"X-Test": {"test-value"}}),
+			WithHeaders(http.Header{"Atproto-Proxy": {"did:web:api.bsky.app#bsky_appview"}}),
+		)
+
+		_, err := lazuliClient.CreateSession(context.Background(), "test-user", "test-password")
+
+		assert.NoError(t, err)
+	})
+}
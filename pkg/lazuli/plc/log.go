@@ -0,0 +1,237 @@
+package plc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDIDNotFound is returned when the PLC directory has no operation log for a DID.
+var ErrDIDNotFound = errors.New("plc: did not found")
+
+const (
+	// DefaultURL is the URL of the public PLC directory.
+	DefaultURL = "https://plc.directory"
+	// RecoveryWindow is how long after an operation a higher priority rotation key can still nullify it.
+	RecoveryWindow = 72 * time.Hour
+
+	defaultTimeout = 10 * time.Second
+	// maxLogSize limits the body read from the audit log endpoint.
+	maxLogSize = 16 << 20
+)
+
+// LogEntry is an operation of the audit log of a DID, as served by the PLC directory.
+type LogEntry struct {
+	DID       string    `json:"did"`
+	Operation Operation `json:"operation"`
+	CID       string    `json:"cid"`
+	// Nullified reports whether the operation was overridden by an operation signed with a higher priority rotation
+	// key within the recovery window.
+	Nullified bool      `json:"nullified"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// VerifyLog checks the audit log of did: the genesis operation must hash to did, every operation must be signed by a
+// rotation key of the operation it follows, the operations in effect must form a single chain, and operations may
+// only be nullified by a higher priority key within the recovery window. It returns the entry in effect, which is the
+// last operation that was not nullified.
+func VerifyLog(did string, log []LogEntry) (*LogEntry, error) {
+	if len(log) == 0 {
+		return nil, fmt.Errorf("%w: %s has an empty log", ErrInvalidOperation, did)
+	}
+
+	type verified struct {
+		entry    *LogEntry
+		keyIndex int
+	}
+	byCID := make(map[string]verified, len(log))
+	var head *LogEntry
+
+	for i := range log {
+		entry := &log[i]
+		op := &entry.Operation
+
+		if entry.DID != did {
+			return nil, fmt.Errorf("%w: entry %d is for %s", ErrInvalidOperation, i, entry.DID)
+		}
+		if err := op.validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		c, err := op.CID()
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if c.String() != entry.CID {
+			return nil, fmt.Errorf("%w: entry %d has cid %s, computed %s", ErrInvalidOperation, i, entry.CID, c)
+		}
+
+		if i == 0 {
+			if err := verifyGenesis(did, entry); err != nil {
+				return nil, err
+			}
+			byCID[entry.CID] = verified{entry: entry}
+			head = entry
+			continue
+		}
+
+		if op.IsGenesis() {
+			return nil, fmt.Errorf("%w: entry %d is a second genesis operation", ErrInvalidOperation, i)
+		}
+		prev, ok := byCID[*op.Prev]
+		if !ok {
+			return nil, fmt.Errorf("%w: entry %d follows unknown operation %s", ErrInvalidOperation, i, *op.Prev)
+		}
+		if prev.entry.Operation.Type == OperationTypeTombstone {
+			return nil, fmt.Errorf("%w: entry %d follows a tombstone", ErrInvalidOperation, i)
+		}
+
+		keyIndex, err := op.VerifySignature(prev.entry.Operation.Keys())
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		byCID[entry.CID] = verified{entry: entry, keyIndex: keyIndex}
+
+		if entry.Nullified {
+			continue
+		}
+
+		if *op.Prev != head.CID {
+			return nil, fmt.Errorf("%w: entry %d does not follow the operation in effect", ErrInvalidOperation, i)
+		}
+		// when the operation replaces nullified ones, the first of them must be recent and signed by a lower
+		// priority key
+		if replaced := findNullified(log[:i], *op.Prev); replaced != nil {
+			if keyIndex >= byCID[replaced.CID].keyIndex {
+				return nil, fmt.Errorf("%w: entry %d nullifies %s without a higher priority key", ErrInvalidOperation, i, replaced.CID)
+			}
+			if entry.CreatedAt.Sub(replaced.CreatedAt) > RecoveryWindow {
+				return nil, fmt.Errorf("%w: entry %d nullifies %s after the recovery window", ErrInvalidOperation, i, replaced.CID)
+			}
+		}
+		head = entry
+	}
+
+	return head, nil
+}
+
+func verifyGenesis(did string, entry *LogEntry) error {
+	op := &entry.Operation
+	if !op.IsGenesis() || entry.Nullified {
+		return fmt.Errorf("%w: first entry is not a genesis operation", ErrInvalidOperation)
+	}
+
+	genesisDID, err := op.DID()
+	if err != nil {
+		return err
+	}
+	if genesisDID != did {
+		return fmt.Errorf("%w: genesis operation is for %s", ErrInvalidOperation, genesisDID)
+	}
+
+	if _, err := op.VerifySignature(op.Keys()); err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+	return nil
+}
+
+// findNullified returns the first nullified entry following the operation prev.
+func findNullified(log []LogEntry, prev string) *LogEntry {
+	for i := range log {
+		op := log[i].Operation
+		if log[i].Nullified && op.Prev != nil && *op.Prev == prev {
+			return &log[i]
+		}
+	}
+	return nil
+}
+
+// ClientOption changes how a Client reaches the PLC directory.
+type ClientOption func(c *Client)
+
+// WithURL makes the client use the PLC directory at directoryURL instead of DefaultURL.
+func WithURL(directoryURL string) ClientOption {
+	return func(c *Client) {
+		c.url = strings.TrimSuffix(directoryURL, "/")
+	}
+}
+
+// WithHTTPClient makes the client send its requests with httpClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// Client reads operation logs from a PLC directory.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient creates a PLC directory client.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{url: DefaultURL}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return c
+}
+
+// AuditLog returns every operation of did, including the nullified ones, in the order they were received by the
+// directory. The log is not verified; use VerifyLog or VerifiedAuditLog for that.
+func (c *Client) AuditLog(ctx context.Context, did string) ([]LogEntry, error) {
+	if !strings.HasPrefix(did, "did:plc:") {
+		return nil, fmt.Errorf("plc: %q is not a did:plc", did)
+	}
+
+	reqURL := fmt.Sprintf("%s/%s/log/audit", c.url, url.PathEscape(did))
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("plc: fail to create audit log request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("plc: fail to get audit log of %s: %w", did, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrDIDNotFound, did)
+	default:
+		return nil, fmt.Errorf("plc: fail to get audit log of %s: unexpected status %d", did, resp.StatusCode)
+	}
+
+	var log []LogEntry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxLogSize)).Decode(&log); err != nil {
+		return nil, fmt.Errorf("plc: fail to decode audit log of %s: %w", did, err)
+	}
+
+	return log, nil
+}
+
+// VerifiedAuditLog returns the audit log of did once it was checked with VerifyLog.
+func (c *Client) VerifiedAuditLog(ctx context.Context, did string) ([]LogEntry, error) {
+	log, err := c.AuditLog(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := VerifyLog(did, log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
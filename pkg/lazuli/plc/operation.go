@@ -0,0 +1,251 @@
+// Package plc reads and verifies the operation logs of did:plc identities, which record every change made to the
+// rotation keys, signing keys, handles and services of an account, signed by one of its rotation keys.
+package plc
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+)
+
+// ErrInvalidOperation is returned when an operation is malformed, badly signed or does not follow the operation
+// before it.
+var ErrInvalidOperation = errors.New("plc: invalid operation")
+
+// operation types
+const (
+	OperationTypeUpdate    = "plc_operation"
+	OperationTypeTombstone = "plc_tombstone"
+	// OperationTypeLegacyCreate is the type of the genesis operations of the first version of did:plc.
+	OperationTypeLegacyCreate = "create"
+)
+
+// maxRotationKeys is the maximum number of rotation keys an operation may hold.
+const maxRotationKeys = 5
+
+var base32Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Service is a service declared by an operation, such as the PDS hosting the account.
+type Service struct {
+	Type     string `json:"type"`
+	Endpoint string `json:"endpoint"`
+}
+
+// Operation is a signed change of a did:plc identity.
+type Operation struct {
+	Type                string             `json:"type"`
+	RotationKeys        []string           `json:"rotationKeys,omitempty"`
+	VerificationMethods map[string]string  `json:"verificationMethods,omitempty"`
+	AlsoKnownAs         []string           `json:"alsoKnownAs,omitempty"`
+	Services            map[string]Service `json:"services,omitempty"`
+
+	// SigningKey, RecoveryKey, Handle and Service are only set by legacy create operations.
+	SigningKey  string `json:"signingKey,omitempty"`
+	RecoveryKey string `json:"recoveryKey,omitempty"`
+	Handle      string `json:"handle,omitempty"`
+	Service     string `json:"service,omitempty"`
+
+	// Prev is the CID of the operation this one follows, or nil for the genesis operation.
+	Prev *string `json:"prev"`
+	// Sig is the base64url signature of the DAG-CBOR encoding of the operation without this field.
+	Sig string `json:"sig,omitempty"`
+
+	// fields holds the operation as it was decoded, so the signed bytes cover fields unknown to Operation.
+	fields map[string]any
+}
+
+// UnmarshalJSON decodes an operation, keeping its original fields to rebuild the bytes it was signed over.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	type operation Operation
+	var decoded operation
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*op = Operation(decoded)
+	op.fields = fields
+	return nil
+}
+
+// IsGenesis reports whether the operation creates the identity.
+func (op *Operation) IsGenesis() bool {
+	return op.Prev == nil
+}
+
+// Keys returns the rotation keys of the operation, which can sign the operation that follows it, in decreasing order
+// of priority. Legacy create operations use their recovery key and then their signing key.
+func (op *Operation) Keys() []string {
+	if op.Type == OperationTypeLegacyCreate {
+		return []string{op.RecoveryKey, op.SigningKey}
+	}
+	return op.RotationKeys
+}
+
+// Doc returns the DID document of did resulting from the operation, or nil for a tombstone.
+func (op *Operation) Doc(did string) *bsky.DIDDoc {
+	methods, aka, services := op.VerificationMethods, op.AlsoKnownAs, op.Services
+	switch op.Type {
+	case OperationTypeTombstone:
+		return nil
+	case OperationTypeLegacyCreate:
+		methods = map[string]string{"atproto": op.SigningKey}
+		aka = []string{"at://" + op.Handle}
+		services = map[string]Service{"atproto_pds": {Type: bsky.DIDServiceTypePDS, Endpoint: op.Service}}
+	}
+
+	doc := &bsky.DIDDoc{
+		Context:     []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/multikey/v1"},
+		ID:          did,
+		AlsoKnownAs: aka,
+	}
+	for _, name := range sortedKeys(methods) {
+		doc.VerificationMethod = append(doc.VerificationMethod, bsky.DIDVerificationMethod{
+			ID:                 did + "#" + name,
+			Type:               bsky.DIDVerificationMethodTypeMultikey,
+			Controller:         did,
+			PublicKeyMultibase: strings.TrimPrefix(methods[name], "did:key:"),
+		})
+	}
+	for _, name := range sortedKeys(services) {
+		doc.Service = append(doc.Service, bsky.DIDService{
+			ID:              "#" + name,
+			Type:            services[name].Type,
+			ServiceEndpoint: services[name].Endpoint,
+		})
+	}
+
+	return doc
+}
+
+// CID returns the CID of the signed operation, which is referenced by the prev field of the operation that follows it.
+func (op *Operation) CID() (cid.CID, error) {
+	signed, err := op.encode(true)
+	if err != nil {
+		return cid.CID{}, err
+	}
+	return cid.Sum(cid.CodecDagCBOR, signed), nil
+}
+
+// DID returns the did:plc identifier created by a genesis operation, which is derived from its hash.
+func (op *Operation) DID() (string, error) {
+	if !op.IsGenesis() {
+		return "", fmt.Errorf("%w: only genesis operations define a did", ErrInvalidOperation)
+	}
+
+	signed, err := op.encode(true)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(signed)
+	return "did:plc:" + strings.ToLower(base32Encoding.EncodeToString(digest[:]))[:24], nil
+}
+
+// VerifySignature checks the signature of the operation against the given rotation keys, returning the index of the
+// key that signed it.
+func (op *Operation) VerifySignature(keys []string) (int, error) {
+	sig, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(op.Sig, "="))
+	if err != nil || len(sig) == 0 {
+		return 0, fmt.Errorf("%w: invalid signature encoding", ErrInvalidOperation)
+	}
+
+	unsigned, err := op.encode(false)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, didKey := range keys {
+		key, err := crypto.ParsePublicDIDKey(didKey)
+		if err != nil {
+			continue
+		}
+		if key.Verify(unsigned, sig) == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %w: no rotation key matches", ErrInvalidOperation, crypto.ErrInvalidSignature)
+}
+
+// validate checks the fields required by the type of the operation.
+func (op *Operation) validate() error {
+	switch op.Type {
+	case OperationTypeUpdate:
+		if len(op.RotationKeys) == 0 || len(op.RotationKeys) > maxRotationKeys {
+			return fmt.Errorf("%w: operation must have between 1 and %d rotation keys", ErrInvalidOperation, maxRotationKeys)
+		}
+		for _, key := range op.RotationKeys {
+			if _, err := crypto.ParsePublicDIDKey(key); err != nil {
+				return fmt.Errorf("%w: rotation key: %w", ErrInvalidOperation, err)
+			}
+		}
+	case OperationTypeTombstone:
+		if op.IsGenesis() {
+			return fmt.Errorf("%w: tombstone has no prev", ErrInvalidOperation)
+		}
+	case OperationTypeLegacyCreate:
+		if !op.IsGenesis() {
+			return fmt.Errorf("%w: create operation has a prev", ErrInvalidOperation)
+		}
+		for _, key := range op.Keys() {
+			if _, err := crypto.ParsePublicDIDKey(key); err != nil {
+				return fmt.Errorf("%w: rotation key: %w", ErrInvalidOperation, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidOperation, op.Type)
+	}
+	return nil
+}
+
+// encode returns the DAG-CBOR encoding of the operation, with or without its signature.
+func (op *Operation) encode(signed bool) ([]byte, error) {
+	fields := op.fields
+	if fields == nil {
+		// the operation was not decoded, so its fields are taken from the struct
+		b, err := json.Marshal(op)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOperation, err)
+		}
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOperation, err)
+		}
+	}
+
+	if !signed {
+		unsigned := make(map[string]any, len(fields))
+		for key, value := range fields {
+			if key != "sig" {
+				unsigned[key] = value
+			}
+		}
+		fields = unsigned
+	}
+
+	b, err := cid.DagCBOR.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOperation, err)
+	}
+	return b, nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
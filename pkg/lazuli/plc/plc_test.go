@@ -0,0 +1,388 @@
+package plc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuilder signs operations with a set of rotation keys and appends them to an audit log.
+type logBuilder struct {
+	t    *testing.T
+	keys []crypto.PrivateKey
+	did  string
+	log  []LogEntry
+	now  time.Time
+}
+
+func newLogBuilder(t *testing.T) *logBuilder {
+	b := &logBuilder{t: t, now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	for i := 0; i < 3; i++ {
+		key, err := crypto.GeneratePrivateKey(crypto.CurveK256)
+		require.NoError(t, err)
+		b.keys = append(b.keys, key)
+	}
+	return b
+}
+
+func (b *logBuilder) didKey(i int) string {
+	return b.keys[i].PublicKey().DIDKey()
+}
+
+func (b *logBuilder) update(handle string) Operation {
+	return Operation{
+		Type:                OperationTypeUpdate,
+		RotationKeys:        []string{b.didKey(0), b.didKey(1)},
+		VerificationMethods: map[string]string{"atproto": b.didKey(2)},
+		AlsoKnownAs:         []string{"at://" + handle},
+		Services:            map[string]Service{"atproto_pds": {Type: bsky.DIDServiceTypePDS, Endpoint: "https://pds.test"}},
+	}
+}
+
+// add signs op with the key at index signer, links it to the entry at index prev when it is not negative, and
+// appends it to the log after the given delay.
+func (b *logBuilder) add(op Operation, signer, prev int, delay time.Duration) *LogEntry {
+	if prev >= 0 {
+		op.Prev = &b.log[prev].CID
+	}
+
+	unsigned, err := op.encode(false)
+	require.NoError(b.t, err)
+	sig, err := b.keys[signer].Sign(unsigned)
+	require.NoError(b.t, err)
+	op.Sig = base64.RawURLEncoding.EncodeToString(sig)
+
+	c, err := op.CID()
+	require.NoError(b.t, err)
+	if op.IsGenesis() {
+		b.did, err = op.DID()
+		require.NoError(b.t, err)
+	}
+
+	b.now = b.now.Add(delay)
+	b.log = append(b.log, LogEntry{DID: b.did, Operation: op, CID: c.String(), CreatedAt: b.now})
+	return &b.log[len(b.log)-1]
+}
+
+func TestVerifyLog(t *testing.T) {
+	type out struct {
+		head   int
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name  string
+		build func(b *logBuilder)
+		out   out
+	}{
+		{
+			name: "Given a log of operations signed by rotation keys, When it is verified, Then it should return the last operation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 1, 0, time.Hour)
+				b.add(b.update("alice3.test"), 0, 1, time.Hour)
+			},
+			out: out{head: 2},
+		},
+		{
+			name: "Given an operation nullified by a higher priority key within the recovery window, When the log is verified, Then it should return the recovery operation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("mallory.test"), 1, 0, time.Hour).Nullified = true
+				b.add(b.update("alice.test"), 0, 0, 24*time.Hour)
+			},
+			out: out{head: 2},
+		},
+		{
+			name: "Given a legacy create operation, When the log is verified, Then its recovery and signing keys should sign the next operation",
+			build: func(b *logBuilder) {
+				b.add(Operation{Type: OperationTypeLegacyCreate, SigningKey: b.didKey(2), RecoveryKey: b.didKey(1), Handle: "alice.test", Service: "https://pds.test"}, 1, -1, 0)
+				b.add(b.update("alice.test"), 2, 0, time.Hour)
+			},
+			out: out{head: 1},
+		},
+		{
+			name: "Given an operation signed by a key that is not a rotation key, When the log is verified, Then it should return ErrInvalidSignature",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("mallory.test"), 2, 0, time.Hour)
+			},
+			out: out{err: crypto.ErrInvalidSignature, errMsg: "entry 1: plc: invalid operation: crypto: invalid signature: no rotation key matches"},
+		},
+		{
+			name: "Given an operation nullified by a key of the same priority, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 1, 0, time.Hour).Nullified = true
+				b.add(b.update("mallory.test"), 1, 0, time.Hour)
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "without a higher priority key"},
+		},
+		{
+			name: "Given an operation nullified after the recovery window, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 1, 0, time.Hour).Nullified = true
+				b.add(b.update("alice.test"), 0, 0, 73*time.Hour)
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "after the recovery window"},
+		},
+		{
+			name: "Given an operation skipping the one in effect, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 1, 0, time.Hour)
+				b.add(b.update("mallory.test"), 0, 0, time.Hour)
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "entry 2 does not follow the operation in effect"},
+		},
+		{
+			name: "Given an operation following a tombstone, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(Operation{Type: OperationTypeTombstone}, 0, 0, time.Hour)
+				b.add(b.update("alice.test"), 0, 1, time.Hour)
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "entry 2 follows a tombstone"},
+		},
+		{
+			name: "Given an entry whose cid does not match its operation, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 0, 0, time.Hour).Operation.AlsoKnownAs = []string{"at://mallory.test"}
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "entry 1 has cid"},
+		},
+		{
+			name: "Given a log starting with an operation that is not a genesis, When it is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.add(b.update("alice.test"), 0, -1, 0)
+				b.add(b.update("alice2.test"), 0, 0, time.Hour)
+				b.log = b.log[1:]
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "plc: invalid operation: first entry is not a genesis operation"},
+		},
+		{
+			name: "Given an operation without rotation keys, When the log is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				op := b.update("alice.test")
+				op.RotationKeys = nil
+				b.add(op, 0, -1, 0)
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "entry 0: plc: invalid operation: operation must have between 1 and 5 rotation keys"},
+		},
+		{
+			name: "Given an empty log, When it is verified, Then it should return ErrInvalidOperation",
+			build: func(b *logBuilder) {
+				b.did = "did:plc:empty"
+			},
+			out: out{err: ErrInvalidOperation, errMsg: "plc: invalid operation: did:plc:empty has an empty log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newLogBuilder(t)
+			tt.build(b)
+
+			// the log goes through JSON, as when it is served by the directory
+			data, err := json.Marshal(b.log)
+			require.NoError(t, err)
+			var log []LogEntry
+			require.NoError(t, json.Unmarshal(data, &log))
+
+			head, err := VerifyLog(b.did, log)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				assert.ErrorContains(t, err, tt.out.errMsg)
+				assert.Nil(t, head)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, log[tt.out.head].CID, head.CID)
+		})
+	}
+
+	t.Run("Given a log of another DID, When it is verified, Then it should return ErrInvalidOperation", func(t *testing.T) {
+		b := newLogBuilder(t)
+		b.add(b.update("alice.test"), 0, -1, 0)
+
+		_, err := VerifyLog("did:plc:aaaaaaaaaaaaaaaaaaaaaaaa", b.log)
+		assert.ErrorIs(t, err, ErrInvalidOperation)
+		assert.ErrorContains(t, err, "entry 0 is for "+b.did)
+
+		b.log[0].DID = "did:plc:aaaaaaaaaaaaaaaaaaaaaaaa"
+		_, err = VerifyLog("did:plc:aaaaaaaaaaaaaaaaaaaaaaaa", b.log)
+		assert.EqualError(t, err, "plc: invalid operation: genesis operation is for "+b.did)
+	})
+}
+
+func TestOperation_DID(t *testing.T) {
+	b := newLogBuilder(t)
+	genesis := b.add(b.update("alice.test"), 0, -1, 0)
+	update := b.add(b.update("alice.test"), 0, 0, 0)
+
+	did, err := genesis.Operation.DID()
+	require.NoError(t, err)
+	assert.Regexp(t, `^did:plc:[a-z2-7]{24}$`, did)
+
+	_, err = update.Operation.DID()
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+}
+
+func TestOperation_Doc(t *testing.T) {
+	const did = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+	const key = "did:key:zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w"
+	prev := "bafyreib2rxk3rybk3aobmv5cjuql3bm2twh4jo5uxgf5kpqcsgz7soitae"
+
+	expected := &bsky.DIDDoc{
+		Context:     []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/multikey/v1"},
+		ID:          did,
+		AlsoKnownAs: []string{"at://alice.test"},
+		VerificationMethod: []bsky.DIDVerificationMethod{{
+			ID:                 did + "#atproto",
+			Type:               bsky.DIDVerificationMethodTypeMultikey,
+			Controller:         did,
+			PublicKeyMultibase: "zQ3shunBKsXixLxKtC5qeSG9E4J5RkGN57im31pcTzbNQnm5w",
+		}},
+		Service: []bsky.DIDService{{ID: "#atproto_pds", Type: bsky.DIDServiceTypePDS, ServiceEndpoint: "https://pds.test"}},
+	}
+
+	tests := []struct {
+		name string
+		in   Operation
+		out  *bsky.DIDDoc
+	}{
+		{
+			name: "Given an update operation, When its document is built, Then it should hold its keys, handle and services",
+			in: Operation{
+				Type:                OperationTypeUpdate,
+				RotationKeys:        []string{key},
+				VerificationMethods: map[string]string{"atproto": key},
+				AlsoKnownAs:         []string{"at://alice.test"},
+				Services:            map[string]Service{"atproto_pds": {Type: bsky.DIDServiceTypePDS, Endpoint: "https://pds.test"}},
+			},
+			out: expected,
+		},
+		{
+			name: "Given a legacy create operation, When its document is built, Then it should hold its signing key, handle and service",
+			in:   Operation{Type: OperationTypeLegacyCreate, SigningKey: key, RecoveryKey: key, Handle: "alice.test", Service: "https://pds.test"},
+			out:  expected,
+		},
+		{
+			name: "Given a tombstone, When its document is built, Then it should return nil",
+			in:   Operation{Type: OperationTypeTombstone, Prev: &prev},
+			out:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := tt.in.Doc(did)
+			assert.Equal(t, tt.out, doc)
+			if doc != nil {
+				signingKey, err := doc.SigningKey()
+				require.NoError(t, err)
+				assert.Equal(t, key, signingKey.DIDKey())
+			}
+		})
+	}
+}
+
+func TestClient_AuditLog(t *testing.T) {
+	b := newLogBuilder(t)
+	b.add(b.update("alice.test"), 0, -1, 0)
+	b.add(b.update("alice2.test"), 1, 0, time.Hour)
+	data, err := json.Marshal(b.log)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + b.did + "/log/audit":
+			_, _ = w.Write(data)
+		case "/did:plc:broken/log/audit":
+			_, _ = w.Write([]byte(`[{`))
+		case "/did:plc:failing/log/audit":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(WithURL(server.URL+"/"), WithHTTPClient(server.Client()))
+
+	type out struct {
+		entries int
+		err     error
+		errMsg  string
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  out
+	}{
+		{
+			name: "Given a DID with a valid log, When its audit log is fetched, Then it should return the verified entries",
+			in:   b.did,
+			out:  out{entries: 2},
+		},
+		{
+			name: "Given an unknown DID, When its audit log is fetched, Then it should return ErrDIDNotFound",
+			in:   "did:plc:unknown",
+			out:  out{err: ErrDIDNotFound, errMsg: "plc: did not found: did:plc:unknown"},
+		},
+		{
+			name: "Given a failing directory, When an audit log is fetched, Then it should return an error",
+			in:   "did:plc:failing",
+			out:  out{errMsg: "plc: fail to get audit log of did:plc:failing: unexpected status 500"},
+		},
+		{
+			name: "Given an invalid response, When an audit log is fetched, Then it should return an error",
+			in:   "did:plc:broken",
+			out:  out{errMsg: "plc: fail to decode audit log of did:plc:broken: unexpected EOF"},
+		},
+		{
+			name: "Given a DID of another method, When its audit log is fetched, Then it should return an error",
+			in:   "did:web:example.com",
+			out:  out{errMsg: `plc: "did:web:example.com" is not a did:plc`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := c.VerifiedAuditLog(context.Background(), tt.in)
+			if tt.out.errMsg != "" {
+				if tt.out.err != nil {
+					assert.ErrorIs(t, err, tt.out.err)
+				}
+				assert.EqualError(t, err, tt.out.errMsg)
+				assert.Nil(t, log)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, log, tt.out.entries)
+			assert.Equal(t, []string{"at://alice2.test"}, log[1].Operation.AlsoKnownAs)
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c := NewClient()
+
+	assert.Equal(t, DefaultURL, c.url)
+	assert.Equal(t, defaultTimeout, c.httpClient.Timeout)
+}
@@ -0,0 +1,370 @@
+package lazuli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/tid"
+)
+
+// WriteRecordOption configures optional fields of the request sent by the calls writing records.
+type WriteRecordOption func(r *bsky.WriteRecordRequest)
+
+// WithSwapRecord makes the write fail with ErrInvalidSwap when the current CID of the record is not c. It cannot be
+// given to ApplyWrites, whose writes are only checked against the commit given by WithSwapCommit.
+func WithSwapRecord(c cid.CID) WriteRecordOption {
+	return func(r *bsky.WriteRecordRequest) {
+		r.SwapRecord = c.String()
+	}
+}
+
+// WithSwapCommit makes the write fail with ErrInvalidSwap when the current commit of the repository is not c.
+func WithSwapCommit(c cid.CID) WriteRecordOption {
+	return func(r *bsky.WriteRecordRequest) {
+		r.SwapCommit = c.String()
+	}
+}
+
+// WithRKey sets the record key of a record created with CreateRecord, instead of letting the server generate one. Using
+// a key generated beforehand, such as tid.Next(), gives the URI of the record before it is created, and makes retrying
+// a creation safe, since the record cannot be created twice. It only applies to CreateRecord: PutRecord and
+// DeleteRecord take the key as an argument and return an error when WithRKey gives another one, and ApplyWrites
+// returns an error when it is given, since each write holds its own key.
+func WithRKey(rkey string) WriteRecordOption {
+	return func(r *bsky.WriteRecordRequest) {
+		r.RKey = rkey
+	}
+}
+
+// WithValidate sets whether the server validates the records against their lexicon schema. By default, records of
+// schemas known by the server are validated.
+func WithValidate(validate bool) WriteRecordOption {
+	return func(r *bsky.WriteRecordRequest) {
+		r.Validate = &validate
+	}
+}
+
+// GetRecord reads the record of repo, which is a DID or a handle, at the given collection and record key.
+func (c *client) GetRecord(ctx context.Context, repo, collection, rkey string) (*bsky.RecordValue, error) {
+	query := url.Values{
+		"repo":       {repo},
+		"collection": {collection},
+		"rkey":       {rkey},
+	}
+	reqURL := fmt.Sprintf("%s/com.atproto.repo.getRecord?%s", c.serviceURL(c.currentSession()), query.Encode())
+
+	req, err := c.newRequest(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to create get record request struct", err.Error())
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to do request to get record", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(resp, "get record request failed")
+	}
+
+	var record bsky.RecordValue
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to decode get record response", err.Error())
+	}
+
+	return &record, nil
+}
+
+// ListRecords lists a page of the records of a collection of repo, which is a DID or a handle. The cursor of the
+// response is given to get the next page, and limit is the size of the page, between 1 and 100, or 0 for the server
+// default.
+func (c *client) ListRecords(ctx context.Context, repo, collection, cursor string, limit int) (*bsky.ListRecordsResponse, error) {
+	if limit < 0 || limit > 100 {
+		return nil, newError(http.StatusBadRequest, "invalid limit query param", "limit must be between 1 and 100")
+	}
+
+	query := url.Values{
+		"repo":       {repo},
+		"collection": {collection},
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	reqURL := fmt.Sprintf("%s/com.atproto.repo.listRecords?%s", c.serviceURL(c.currentSession()), query.Encode())
+
+	req, err := c.newRequest(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to create list records request struct", err.Error())
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to do request to list records", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(resp, "list records request failed")
+	}
+
+	var listResponse bsky.ListRecordsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&listResponse); err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to decode list records response", err.Error())
+	}
+
+	return &listResponse, nil
+}
+
+// CreateRecord creates record in a collection of the session repository, under a record key generated by the server.
+// The record can be of any type encoding to a JSON object, such as bsky.PostRecord or a map, and its $type is set to
+// the collection when it is empty.
+func (c *client) CreateRecord(ctx context.Context, collection string, record any, opts ...WriteRecordOption) (*bsky.CreateRecordResponse, error) {
+	value, err := typedRecord(collection, record)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "fail to encode create record request", err.Error())
+	}
+
+	request := bsky.WriteRecordRequest{
+		Collection: collection,
+		Record:     value,
+	}
+	for _, opt := range opts {
+		opt(&request)
+	}
+	if request.RKey != "" {
+		if err := tid.ValidateRecordKey(request.RKey); err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid rkey", err.Error())
+		}
+	}
+
+	var createResponse bsky.CreateRecordResponse
+	if err := c.writeRecord(ctx, "com.atproto.repo.createRecord", "create record", func(repo string) any {
+		request.Repo = repo
+		return request
+	}, &createResponse); err != nil {
+		return nil, err
+	}
+
+	return &createResponse, nil
+}
+
+// PutRecord creates or replaces the record of the session repository at the given collection and record key, which
+// is how records with fixed keys, such as the "self" profile record, are written. Like with CreateRecord, the $type
+// of the record is set to the collection when it is empty.
+func (c *client) PutRecord(ctx context.Context, collection, rkey string, record any, opts ...WriteRecordOption) (*bsky.PutRecordResponse, error) {
+	value, err := typedRecord(collection, record)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "fail to encode put record request", err.Error())
+	}
+
+	request := bsky.WriteRecordRequest{
+		Collection: collection,
+		RKey:       rkey,
+		Record:     value,
+	}
+
+	for _, opt := range opts {
+		opt(&request)
+	}
+	if request.RKey != rkey {
+		return nil, newError(http.StatusBadRequest, "invalid rkey", "the record key of PutRecord is its rkey argument, not WithRKey")
+	}
+	if err := tid.ValidateRecordKey(rkey); err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid rkey", err.Error())
+	}
+
+	var putResponse bsky.PutRecordResponse
+	if err := c.writeRecord(ctx, "com.atproto.repo.putRecord", "put record", func(repo string) any {
+		request.Repo = repo
+		return request
+	}, &putResponse); err != nil {
+		return nil, err
+	}
+
+	return &putResponse, nil
+}
+
+// DeleteRecord deletes the record of the session repository at the given collection and record key, such as a like
+// to undo it. Deleting a record that does not exist succeeds with no commit.
+func (c *client) DeleteRecord(ctx context.Context, collection, rkey string, opts ...WriteRecordOption) (*bsky.DeleteRecordResponse, error) {
+	request := bsky.WriteRecordRequest{
+		Collection: collection,
+		RKey:       rkey,
+	}
+
+	for _, opt := range opts {
+		opt(&request)
+	}
+	if request.RKey != rkey {
+		return nil, newError(http.StatusBadRequest, "invalid rkey", "the record key of DeleteRecord is its rkey argument, not WithRKey")
+	}
+	if err := tid.ValidateRecordKey(rkey); err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid rkey", err.Error())
+	}
+
+	var deleteResponse bsky.DeleteRecordResponse
+	if err := c.writeRecord(ctx, "com.atproto.repo.deleteRecord", "delete record", func(repo string) any {
+		request.Repo = repo
+		return request
+	}, &deleteResponse); err != nil {
+		return nil, err
+	}
+
+	return &deleteResponse, nil
+}
+
+// maxWritesPerCall is the number of writes accepted by a com.atproto.repo.applyWrites call.
+const maxWritesPerCall = 200
+
+// ApplyWrites creates, updates and deletes records of the session repository with com.atproto.repo.applyWrites. The
+// writes are sent in calls of up to 200 writes, each of them applied atomically in a single commit, and the results
+// are returned in the order of the writes. The $type of the records is set to their collection when it is empty.
+// WithRKey and WithSwapRecord return an error, since each write holds its own key. When a swap commit is given, each
+// call after the first one is checked against the commit of the call before it. When a call fails after others
+// succeeded, the response holding the results of the applied writes is returned along with the error.
+func (c *client) ApplyWrites(ctx context.Context, writes []bsky.WriteOp, opts ...WriteRecordOption) (*bsky.ApplyWritesResponse, error) {
+	if len(writes) == 0 {
+		return nil, newError(http.StatusBadRequest, "invalid writes", "writes must have at least one value")
+	}
+
+	var options bsky.WriteRecordRequest
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.RKey != "" {
+		return nil, newError(http.StatusBadRequest, "invalid rkey", "the record keys of ApplyWrites are those of its writes, not WithRKey")
+	}
+	if options.SwapRecord != "" {
+		return nil, newError(http.StatusBadRequest, "invalid swap record", "the writes of ApplyWrites are only checked against WithSwapCommit, not WithSwapRecord")
+	}
+
+	typed := make([]bsky.WriteOp, len(writes))
+	for i, w := range writes {
+		typed[i] = w
+		if w.RKey != "" || w.Type != bsky.WriteTypeCreate {
+			if err := tid.ValidateRecordKey(w.RKey); err != nil {
+				return nil, newError(http.StatusBadRequest, "invalid rkey", fmt.Sprintf("write %d: %s", i, err))
+			}
+		}
+		if w.Type == bsky.WriteTypeDelete {
+			continue
+		}
+		value, err := typedRecord(w.Collection, w.Value)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, "fail to encode apply writes request", fmt.Sprintf("write %d: %s", i, err))
+		}
+		typed[i].Value = value
+	}
+	writes = typed
+
+	applied := &bsky.ApplyWritesResponse{Results: make([]bsky.WriteResult, 0, len(writes))}
+	swapCommit := options.SwapCommit
+	for start := 0; start < len(writes); start += maxWritesPerCall {
+		chunk := writes[start:min(start+maxWritesPerCall, len(writes))]
+		request := bsky.ApplyWritesRequest{Validate: options.Validate, Writes: chunk, SwapCommit: swapCommit}
+
+		var response bsky.ApplyWritesResponse
+		err := c.writeRecord(ctx, "com.atproto.repo.applyWrites", "apply writes", func(repo string) any {
+			request.Repo = repo
+			return request
+		}, &response)
+		if err == nil && len(response.Results) != 0 && len(response.Results) != len(chunk) {
+			err = newError(http.StatusInternalServerError, "fail to decode apply writes response", fmt.Sprintf("got %d results for %d writes", len(response.Results), len(chunk)))
+		}
+		if err != nil {
+			if start == 0 {
+				return nil, err
+			}
+			return applied, err
+		}
+
+		if len(response.Results) == 0 {
+			// servers implementing older versions of applyWrites send no results
+			for _, w := range chunk {
+				response.Results = append(response.Results, bsky.WriteResult{Type: w.Type + "Result"})
+			}
+		}
+		applied.Results = append(applied.Results, response.Results...)
+		applied.Commit = response.Commit
+		if swapCommit != "" && response.Commit != nil {
+			swapCommit = response.Commit.CID.String()
+		}
+	}
+
+	return applied, nil
+}
+
+// writeRecord sends the request returned by body for the session repository with the given method, and decodes the
+// response into out. action names the call in the errors returned.
+func (c *client) writeRecord(ctx context.Context, method, action string, body func(repo string) any, out any) error {
+	return c.withSessionRefresh(ctx, func(sess *bsky.AuthResponse) error {
+		jsonBody, err := json.Marshal(body(sess.DID))
+		if err != nil {
+			return newError(http.StatusBadRequest, "fail to encode "+action+" request", err.Error())
+		}
+
+		reqURL := fmt.Sprintf("%s/%s", c.serviceURL(sess), method)
+		req, err := c.newRequest(ctx, "POST", reqURL, bytes.NewBuffer(jsonBody))
+		if err != nil {
+			return newError(http.StatusInternalServerError, "fail to create request struct to "+action, err.Error())
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sess.AccessJwt))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return newError(http.StatusInternalServerError, "fail to do request to "+action, err.Error())
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return newErrorFromResponse(resp, action+" request failed")
+		}
+
+		// servers implementing older versions of deleteRecord send no body
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+			return newError(http.StatusInternalServerError, "fail to decode "+action+" response", err.Error())
+		}
+
+		return nil
+	})
+}
+
+// typedRecord returns the JSON encoding of record with its $type set to collection when it is empty, so typed records
+// can be written without filling it.
+func typedRecord(collection string, record any) (json.RawMessage, error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("record of %s must encode to a JSON object", collection)
+	}
+
+	var lexiconType string
+	if raw, ok := fields["$type"]; ok {
+		_ = json.Unmarshal(raw, &lexiconType)
+	}
+	if lexiconType != "" {
+		return b, nil
+	}
+
+	fields["$type"], _ = json.Marshal(collection)
+	return json.Marshal(fields)
+}
@@ -0,0 +1,69 @@
+// Package repo verifies AT Protocol repositories and the commits that change them.
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ErrInvalidCommit is returned when a commit is malformed or does not match the event or repository it came with.
+var ErrInvalidCommit = errors.New("repo: invalid commit")
+
+// Commit is the signed root of a repository, pointing to the MST holding its records.
+type Commit struct {
+	DID     string  `cbor:"did"`
+	Version int64   `cbor:"version"`
+	Data    cid.CID `cbor:"data"`
+	Rev     string  `cbor:"rev"`
+	Prev    cid.CID `cbor:"prev"`
+	Sig     []byte  `cbor:"sig"`
+
+	unsigned []byte
+}
+
+// DecodeCommit decodes a DAG-CBOR commit block.
+func DecodeCommit(data []byte) (*Commit, error) {
+	var commit Commit
+	if err := cbor.Unmarshal(data, &commit); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommit, err)
+	}
+	if commit.Version != 3 && commit.Version != 2 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCommit, commit.Version)
+	}
+	if commit.DID == "" || !commit.Data.Defined() {
+		return nil, fmt.Errorf("%w: did and data are required", ErrInvalidCommit)
+	}
+
+	// the signed bytes are rebuilt from the original fields, so fields unknown to Commit are still covered
+	var fields map[string]cbor.RawMessage
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommit, err)
+	}
+	delete(fields, "sig")
+
+	unsigned, err := cid.DagCBOR.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fail to encode unsigned commit: %w", ErrInvalidCommit, err)
+	}
+	commit.unsigned = unsigned
+
+	return &commit, nil
+}
+
+// UnsignedBytes returns the DAG-CBOR encoding of the commit without its signature, which is the content signed by
+// the account.
+func (c *Commit) UnsignedBytes() []byte {
+	return c.unsigned
+}
+
+// VerifySignature checks the signature of the commit against the signing key of the account.
+func (c *Commit) VerifySignature(key crypto.PublicKey) error {
+	if len(c.Sig) == 0 {
+		return fmt.Errorf("%w: commit is not signed", crypto.ErrInvalidSignature)
+	}
+	return key.Verify(c.unsigned, c.Sig)
+}
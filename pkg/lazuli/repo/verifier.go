@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/mst"
+)
+
+var (
+	// ErrTooBig is returned for commits too big to be sent through the firehose, which come without their blocks
+	// and must be verified by fetching the repository instead.
+	ErrTooBig = errors.New("repo: commit is too big to be verified from the event")
+	// ErrInvalidOperation is returned when an operation of a commit is not consistent with the MST of the commit.
+	ErrInvalidOperation = errors.New("repo: operation does not match the commit tree")
+)
+
+// DIDResolver resolves a DID to its DID document.
+type DIDResolver interface {
+	ResolveDID(ctx context.Context, did string) (*bsky.DIDDoc, error)
+}
+
+// VerifierOption changes how a Verifier checks commits.
+type VerifierOption func(v *Verifier)
+
+// WithOperationProofs makes the verifier also check that every operation of a commit is consistent with the MST
+// proof nodes sent along with it: created and updated records must be in the tree with the CID of the operation, and
+// deleted records must not be in it.
+func WithOperationProofs() VerifierOption {
+	return func(v *Verifier) {
+		v.checkOperations = true
+	}
+}
+
+// Verifier checks that firehose commits were signed by the owner of the repository.
+type Verifier struct {
+	resolver        DIDResolver
+	checkOperations bool
+}
+
+// NewVerifier creates a verifier resolving signing keys with resolver.
+func NewVerifier(resolver DIDResolver, opts ...VerifierOption) *Verifier {
+	v := &Verifier{resolver: resolver}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// VerifyCommitEvent decodes the signed commit of evt from its blocks and checks its signature against the signing
+// key in the DID document of the repository, returning the verified commit.
+func (v *Verifier) VerifyCommitEvent(ctx context.Context, evt bsky.RepoCommitEvent) (*Commit, error) {
+	if evt.TooBig {
+		return nil, ErrTooBig
+	}
+
+	blocks, err := evt.ReadBlocks()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCommit, err)
+	}
+
+	data, ok := blocks.Get(evt.Commit)
+	if !ok {
+		return nil, fmt.Errorf("%w: commit block %s not found", ErrInvalidCommit, evt.Commit)
+	}
+	commit, err := DecodeCommit(data)
+	if err != nil {
+		return nil, err
+	}
+	if commit.DID != evt.Repo {
+		return nil, fmt.Errorf("%w: commit of %s sent for repository %s", ErrInvalidCommit, commit.DID, evt.Repo)
+	}
+	if evt.Rev != "" && commit.Rev != evt.Rev {
+		return nil, fmt.Errorf("%w: commit revision %s does not match event revision %s", ErrInvalidCommit, commit.Rev, evt.Rev)
+	}
+
+	doc, err := v.resolver.ResolveDID(ctx, commit.DID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: fail to resolve %s: %w", commit.DID, err)
+	}
+	key, err := doc.SigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("repo: fail to read signing key of %s: %w", commit.DID, err)
+	}
+	if err := commit.VerifySignature(key); err != nil {
+		return nil, err
+	}
+
+	if v.checkOperations {
+		if err := verifyOperations(mst.Load(blocks, commit.Data), evt.Ops); err != nil {
+			return nil, err
+		}
+	}
+
+	return commit, nil
+}
+
+func verifyOperations(tree *mst.Tree, ops []bsky.RepoOperation) error {
+	for _, op := range ops {
+		value, err := tree.Get(op.Path)
+		switch {
+		case op.Action == bsky.RepoOperationActionDelete:
+			if err == nil {
+				return fmt.Errorf("%w: deleted record %s is still in the tree", ErrInvalidOperation, op.Path)
+			}
+			if !errors.Is(err, mst.ErrNotFound) {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidOperation, op.Path, err)
+			}
+		case err != nil:
+			return fmt.Errorf("%w: %s: %w", ErrInvalidOperation, op.Path, err)
+		case value != op.CID:
+			return fmt.Errorf("%w: %s is %s in the tree, not %s", ErrInvalidOperation, op.Path, value, op.CID)
+		}
+	}
+	return nil
+}
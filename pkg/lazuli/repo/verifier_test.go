@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDID = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+type resolverMock map[string]*bsky.DIDDoc
+
+func (m resolverMock) ResolveDID(_ context.Context, did string) (*bsky.DIDDoc, error) {
+	doc, ok := m[did]
+	if !ok {
+		return nil, errors.New("did not found")
+	}
+	return doc, nil
+}
+
+func didDoc(did, methodType, multibase string) *bsky.DIDDoc {
+	return &bsky.DIDDoc{
+		ID: did,
+		VerificationMethod: []bsky.DIDVerificationMethod{
+			{ID: did + "#atproto", Type: methodType, Controller: did, PublicKeyMultibase: multibase},
+		},
+	}
+}
+
+func encodeBlock(t *testing.T, v any) car.Block {
+	data, err := cid.DagCBOR.Marshal(v)
+	require.NoError(t, err)
+	return car.Block{CID: cid.Sum(cid.CodecDagCBOR, data), Data: data}
+}
+
+// signCommit encodes and signs a commit of did pointing to the tree data.
+func signCommit(t *testing.T, key crypto.PrivateKey, did, rev string, data cid.CID) car.Block {
+	commit := map[string]any{"did": did, "version": 3, "data": data, "rev": rev, "prev": nil}
+	unsigned, err := cid.DagCBOR.Marshal(commit)
+	require.NoError(t, err)
+
+	commit["sig"], err = key.Sign(unsigned)
+	require.NoError(t, err)
+	return encodeBlock(t, commit)
+}
+
+func writeCAR(t *testing.T, root cid.CID, blocks ...car.Block) []byte {
+	var buf bytes.Buffer
+	w, err := car.NewWriter(&buf, root)
+	require.NoError(t, err)
+	for _, block := range blocks {
+		require.NoError(t, w.Write(block))
+	}
+	return buf.Bytes()
+}
+
+func TestVerifierVerifyCommitEvent(t *testing.T) {
+	k256, err := crypto.GeneratePrivateKey(crypto.CurveK256)
+	require.NoError(t, err)
+	p256, err := crypto.GeneratePrivateKey(crypto.CurveP256)
+	require.NoError(t, err)
+
+	record := encodeBlock(t, map[string]any{"$type": bsky.CollectionPost, "text": "hello"})
+	node := encodeBlock(t, map[string]any{
+		"l": nil,
+		"e": []map[string]any{{"p": 0, "k": []byte("app.bsky.feed.post/3k"), "v": record.CID, "t": nil}},
+	})
+
+	k256Commit := signCommit(t, k256, testDID, "3kabc", node.CID)
+	p256Commit := signCommit(t, p256, "did:web:example.com", "3kabc", node.CID)
+
+	resolver := resolverMock{
+		testDID:               didDoc(testDID, bsky.DIDVerificationMethodTypeMultikey, k256.PublicKey().Multibase()),
+		"did:web:example.com": didDoc("did:web:example.com", bsky.DIDVerificationMethodTypeMultikey, p256.PublicKey().Multibase()),
+		"did:plc:nokey":       {ID: "did:plc:nokey"},
+	}
+
+	create := bsky.RepoOperation{Action: bsky.RepoOperationActionCreate, Path: "app.bsky.feed.post/3k", CID: record.CID}
+	event := func(repo string, commit car.Block, ops ...bsky.RepoOperation) bsky.RepoCommitEvent {
+		return bsky.RepoCommitEvent{
+			Repo:   repo,
+			Rev:    "3kabc",
+			Commit: commit.CID,
+			Ops:    ops,
+			Blocks: writeCAR(t, commit.CID, commit, node, record),
+		}
+	}
+
+	type in struct {
+		opts []VerifierOption
+		evt  bsky.RepoCommitEvent
+	}
+
+	type out struct {
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given a commit signed with the secp256k1 key of the repository, When it is verified, Then it should return the commit",
+			in:   in{evt: event(testDID, k256Commit, create)},
+		},
+		{
+			name: "Given a commit signed with the P-256 key of the repository, When it is verified, Then it should return the commit",
+			in:   in{evt: event("did:web:example.com", p256Commit, create)},
+		},
+		{
+			name: "Given a commit signed with another key, When it is verified, Then it should return ErrInvalidSignature",
+			in:   in{evt: event(testDID, signCommit(t, p256, testDID, "3kabc", node.CID), create)},
+			out:  out{err: crypto.ErrInvalidSignature},
+		},
+		{
+			name: "Given a commit of another repository, When it is verified, Then it should return ErrInvalidCommit",
+			in:   in{evt: event("did:web:example.com", k256Commit, create)},
+			out:  out{err: ErrInvalidCommit},
+		},
+		{
+			name: "Given a commit with another revision, When it is verified, Then it should return ErrInvalidCommit",
+			in:   in{evt: event(testDID, signCommit(t, k256, testDID, "3kxyz", node.CID), create)},
+			out:  out{err: ErrInvalidCommit},
+		},
+		{
+			name: "Given an event without the commit block, When it is verified, Then it should return ErrInvalidCommit",
+			in: in{evt: bsky.RepoCommitEvent{
+				Repo:   testDID,
+				Commit: k256Commit.CID,
+				Blocks: writeCAR(t, k256Commit.CID, node),
+			}},
+			out: out{err: ErrInvalidCommit},
+		},
+		{
+			name: "Given an event with malformed blocks, When it is verified, Then it should return ErrInvalidCommit",
+			in:   in{evt: bsky.RepoCommitEvent{Repo: testDID, Commit: k256Commit.CID, Blocks: []byte{0x01}}},
+			out:  out{err: ErrInvalidCommit},
+		},
+		{
+			name: "Given a too big event, When it is verified, Then it should return ErrTooBig",
+			in:   in{evt: bsky.RepoCommitEvent{Repo: testDID, TooBig: true}},
+			out:  out{err: ErrTooBig},
+		},
+		{
+			name: "Given a repository whose DID cannot be resolved, When it is verified, Then it should return the resolution error",
+			in:   in{evt: event("did:plc:unknown", signCommit(t, k256, "did:plc:unknown", "3kabc", node.CID))},
+			out:  out{errMsg: "repo: fail to resolve did:plc:unknown: did not found"},
+		},
+		{
+			name: "Given a DID document without a signing key, When it is verified, Then it should return an error",
+			in:   in{evt: event("did:plc:nokey", signCommit(t, k256, "did:plc:nokey", "3kabc", node.CID))},
+			out:  out{errMsg: "repo: fail to read signing key of did:plc:nokey: did document did:plc:nokey has no atproto signing key"},
+		},
+		{
+			name: "Given operations consistent with the tree, When they are verified, Then it should return the commit",
+			in: in{opts: []VerifierOption{WithOperationProofs()}, evt: event(testDID, k256Commit, create,
+				bsky.RepoOperation{Action: bsky.RepoOperationActionDelete, Path: "app.bsky.feed.post/3j"},
+			)},
+		},
+		{
+			name: "Given an operation with another record CID, When it is verified, Then it should return ErrInvalidOperation",
+			in: in{opts: []VerifierOption{WithOperationProofs()}, evt: event(testDID, k256Commit,
+				bsky.RepoOperation{Action: bsky.RepoOperationActionUpdate, Path: "app.bsky.feed.post/3k", CID: node.CID},
+			)},
+			out: out{err: ErrInvalidOperation},
+		},
+		{
+			name: "Given a deletion of a record still in the tree, When it is verified, Then it should return ErrInvalidOperation",
+			in: in{opts: []VerifierOption{WithOperationProofs()}, evt: event(testDID, k256Commit,
+				bsky.RepoOperation{Action: bsky.RepoOperationActionDelete, Path: "app.bsky.feed.post/3k"},
+			)},
+			out: out{err: ErrInvalidOperation},
+		},
+		{
+			name: "Given a creation of a record missing from the tree, When it is verified, Then it should return ErrInvalidOperation",
+			in: in{opts: []VerifierOption{WithOperationProofs()}, evt: event(testDID, k256Commit,
+				bsky.RepoOperation{Action: bsky.RepoOperationActionCreate, Path: "app.bsky.feed.post/3z", CID: record.CID},
+			)},
+			out: out{err: ErrInvalidOperation},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := NewVerifier(resolver, tt.in.opts...).VerifyCommitEvent(context.Background(), tt.in.evt)
+			if tt.out.err != nil || tt.out.errMsg != "" {
+				if tt.out.err != nil {
+					assert.ErrorIs(t, err, tt.out.err)
+				}
+				if tt.out.errMsg != "" {
+					assert.EqualError(t, err, tt.out.errMsg)
+				}
+				assert.Nil(t, commit)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.in.evt.Repo, commit.DID)
+			assert.Equal(t, node.CID, commit.Data)
+			assert.False(t, commit.Prev.Defined())
+		})
+	}
+}
+
+func TestDecodeCommit(t *testing.T) {
+	key, err := crypto.GeneratePrivateKey(crypto.CurveK256)
+	require.NoError(t, err)
+	data := cid.Sum(cid.CodecDagCBOR, []byte("tree"))
+
+	// fields unknown to Commit must still be part of the signed bytes
+	extended := map[string]any{"did": testDID, "version": 3, "data": data, "rev": "3kabc", "prev": nil, "extra": "field"}
+	unsigned, err := cid.DagCBOR.Marshal(extended)
+	require.NoError(t, err)
+	extended["sig"], err = key.Sign(unsigned)
+	require.NoError(t, err)
+
+	type out struct {
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name string
+		in   car.Block
+		out  out
+	}{
+		{
+			name: "Given a signed commit with unknown fields, When it is decoded, Then its signature should be valid",
+			in:   encodeBlock(t, extended),
+		},
+		{
+			name: "Given an unsigned commit, When its signature is verified, Then it should return ErrInvalidSignature",
+			in:   encodeBlock(t, map[string]any{"did": testDID, "version": 3, "data": data, "rev": "3kabc"}),
+			out:  out{err: crypto.ErrInvalidSignature},
+		},
+		{
+			name: "Given a commit of an unsupported version, When it is decoded, Then it should return ErrInvalidCommit",
+			in:   encodeBlock(t, map[string]any{"did": testDID, "version": 1, "data": data}),
+			out:  out{err: ErrInvalidCommit, errMsg: "repo: invalid commit: unsupported version 1"},
+		},
+		{
+			name: "Given a commit without its data, When it is decoded, Then it should return ErrInvalidCommit",
+			in:   encodeBlock(t, map[string]any{"did": testDID, "version": 3}),
+			out:  out{err: ErrInvalidCommit, errMsg: "repo: invalid commit: did and data are required"},
+		},
+		{
+			name: "Given a block that is not a commit, When it is decoded, Then it should return ErrInvalidCommit",
+			in:   encodeBlock(t, "not a commit"),
+			out:  out{err: ErrInvalidCommit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := DecodeCommit(tt.in.Data)
+			if err == nil {
+				assert.NotEmpty(t, commit.UnsignedBytes())
+				err = commit.VerifySignature(key.PublicKey())
+			}
+
+			if tt.out.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.out.err)
+			if tt.out.errMsg != "" {
+				assert.EqualError(t, err, tt.out.errMsg)
+			}
+		})
+	}
+}
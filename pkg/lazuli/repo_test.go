@@ -0,0 +1,689 @@
+package lazuli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetRecord(t *testing.T) {
+	record := bsky.RecordValue{
+		URI:   "at://test-did/app.bsky.actor.profile/self",
+		CID:   cid.Sum(cid.CodecDagCBOR, []byte("record")),
+		Value: json.RawMessage(`{"$type":"app.bsky.actor.profile","displayName":"Test"}`),
+	}
+
+	type out struct {
+		record *bsky.RecordValue
+		err    error
+	}
+
+	tests := []struct {
+		name    string
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given an existing record, When GetRecord is called, Then it should return the record",
+			out:  out{record: &record},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.repo.getRecord", r.URL.Path)
+				assert.Equal(t, "collection=app.bsky.actor.profile&repo=test-did&rkey=self", r.URL.RawQuery)
+				_ = json.NewEncoder(w).Encode(record)
+			},
+		},
+		{
+			name: "Given a record that does not exist, When GetRecord is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusBadRequest, "get record request failed", `{"error":"RecordNotFound","message":"Could not locate record"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "RecordNotFound", "message": "Could not locate record"})
+			},
+		},
+		{
+			name: "Given an invalid response, When GetRecord is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "fail to decode get record response", "unexpected EOF"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"uri":`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.GetRecord(context.Background(), "test-did", bsky.CollectionProfile, "self")
+			assert.Equal(t, tt.out.err, err)
+			assert.Equal(t, tt.out.record, result)
+		})
+	}
+}
+
+func TestClient_ListRecords(t *testing.T) {
+	page := bsky.ListRecordsResponse{
+		Cursor: "3l3qo2vutsw2b",
+		Records: []bsky.RecordValue{{
+			URI:   "at://test-did/app.bsky.feed.like/3l3qo2vutsw2b",
+			CID:   cid.Sum(cid.CodecDagCBOR, []byte("like")),
+			Value: json.RawMessage(`{"$type":"app.bsky.feed.like"}`),
+		}},
+	}
+
+	type in struct {
+		cursor string
+		limit  int
+	}
+
+	type out struct {
+		response *bsky.ListRecordsResponse
+		err      error
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a cursor and a limit, When ListRecords is called, Then it should return the page",
+			in:   in{cursor: "3l3qo2vuowo2b", limit: 50},
+			out:  out{response: &page},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.repo.listRecords", r.URL.Path)
+				assert.Equal(t, "collection=app.bsky.feed.like&cursor=3l3qo2vuowo2b&limit=50&repo=test-did", r.URL.RawQuery)
+				_ = json.NewEncoder(w).Encode(page)
+			},
+		},
+		{
+			name: "Given an invalid limit, When ListRecords is called, Then it should return an error",
+			in:   in{limit: 101},
+			out: out{
+				err: newError(http.StatusBadRequest, "invalid limit query param", "limit must be between 1 and 100"),
+			},
+		},
+		{
+			name: "Given a failing server, When ListRecords is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "list records request failed", `{"message":"request failed"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"message": "request failed"})
+			},
+		},
+		{
+			name: "Given an invalid response, When ListRecords is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "fail to decode list records response", "unexpected EOF"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"records":`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.ListRecords(context.Background(), "test-did", bsky.CollectionLike, tt.in.cursor, tt.in.limit)
+			assert.Equal(t, tt.out.err, err)
+			assert.Equal(t, tt.out.response, result)
+		})
+	}
+}
+
+func TestClient_PutRecord(t *testing.T) {
+	swapRecord := cid.Sum(cid.CodecDagCBOR, []byte("old record"))
+	swapCommit := cid.Sum(cid.CodecDagCBOR, []byte("old commit"))
+	response := bsky.PutRecordResponse{
+		URI:              "at://test-did/app.bsky.actor.profile/self",
+		CID:              cid.Sum(cid.CodecDagCBOR, []byte("record")),
+		Commit:           &bsky.CommitMeta{CID: cid.Sum(cid.CodecDagCBOR, []byte("commit")), Rev: "3l3qo2vuowo2b"},
+		ValidationStatus: bsky.ValidationStatusValid,
+	}
+
+	type out struct {
+		response *bsky.PutRecordResponse
+		err      error
+	}
+
+	tests := []struct {
+		name    string
+		opts    []WriteRecordOption
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a record and swap CIDs, When PutRecord is called, Then it should send them and return the response",
+			opts: []WriteRecordOption{WithSwapRecord(swapRecord), WithSwapCommit(swapCommit)},
+			out:  out{response: &response},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.repo.putRecord", r.URL.Path)
+				assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+				var request map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				assert.Equal(t, map[string]any{
+					"repo":       "test-did",
+					"collection": "app.bsky.actor.profile",
+					"rkey":       "self",
+					"record":     map[string]any{"$type": "app.bsky.actor.profile", "displayName": "Test"},
+					"swapRecord": swapRecord.String(),
+					"swapCommit": swapCommit.String(),
+				}, request)
+
+				_ = json.NewEncoder(w).Encode(response)
+			},
+		},
+		{
+			name: "Given a record changed since it was read, When PutRecord is called, Then it should return ErrInvalidSwap",
+			opts: []WriteRecordOption{WithSwapRecord(swapRecord)},
+			out: out{
+				err: newError(http.StatusBadRequest, "put record request failed", `{"error":"InvalidSwap","message":"Record was at bafy"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidSwap", "message": "Record was at bafy"})
+			},
+		},
+		{
+			name: "Given an invalid response, When PutRecord is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "fail to decode put record response", "unexpected EOF"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"uri":`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.PutRecord(context.Background(), bsky.CollectionProfile, "self", map[string]any{"displayName": "Test"}, tt.opts...)
+			assert.Equal(t, tt.out.err, err)
+			assert.Equal(t, tt.out.response, result)
+			if tt.out.err != nil && tt.opts != nil {
+				assert.ErrorIs(t, err, ErrInvalidSwap)
+			}
+		})
+	}
+}
+
+func TestClient_DeleteRecord(t *testing.T) {
+	commit := &bsky.CommitMeta{CID: cid.Sum(cid.CodecDagCBOR, []byte("commit")), Rev: "3l3qo2vuowo2b"}
+
+	type out struct {
+		response *bsky.DeleteRecordResponse
+		err      error
+	}
+
+	tests := []struct {
+		name    string
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given an existing record, When DeleteRecord is called, Then it should return the commit deleting it",
+			out:  out{response: &bsky.DeleteRecordResponse{Commit: commit}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.repo.deleteRecord", r.URL.Path)
+
+				var request map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				assert.Equal(t, map[string]any{"repo": "test-did", "collection": "app.bsky.feed.like", "rkey": "3l3qo2vutsw2b"}, request)
+
+				_ = json.NewEncoder(w).Encode(bsky.DeleteRecordResponse{Commit: commit})
+			},
+		},
+		{
+			name: "Given a server sending no body, When DeleteRecord is called, Then it should return an empty response",
+			out:  out{response: &bsky.DeleteRecordResponse{}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "Given a failing server, When DeleteRecord is called, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "delete record request failed", `{"message":"request failed"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"message": "request failed"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.DeleteRecord(context.Background(), bsky.CollectionLike, "3l3qo2vutsw2b")
+			assert.Equal(t, tt.out.err, err)
+			assert.Equal(t, tt.out.response, result)
+		})
+	}
+
+	t.Run("Given no session, When DeleteRecord is called, Then it should return an error", func(t *testing.T) {
+		lazuliClient := &client{httpClient: http.DefaultClient}
+
+		_, err := lazuliClient.DeleteRecord(context.Background(), bsky.CollectionLike, "3l3qo2vutsw2b")
+		assert.Equal(t, errNoSession(), err)
+	})
+}
+
+func TestClient_ApplyWrites(t *testing.T) {
+	swapCommit := cid.Sum(cid.CodecDagCBOR, []byte("commit 0"))
+
+	// writes creates n writes, alternating creations and deletions
+	writes := func(n int) []bsky.WriteOp {
+		ops := make([]bsky.WriteOp, n)
+		for i := range ops {
+			if i%2 == 0 {
+				ops[i] = bsky.NewCreateWriteOp(bsky.CollectionLike, map[string]any{"n": i})
+			} else {
+				ops[i] = bsky.NewDeleteWriteOp(bsky.CollectionLike, fmt.Sprint(i))
+			}
+		}
+		return ops
+	}
+
+	// server answers every call with a new commit, and the result of each write holding its position in the input
+	type server struct {
+		calls     []bsky.ApplyWritesRequest
+		failAt    int
+		noResults bool
+		extra     bool
+	}
+	handler := func(s *server) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/com.atproto.repo.applyWrites", r.URL.Path)
+
+			var request bsky.ApplyWritesRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			s.calls = append(s.calls, request)
+			if len(s.calls) == s.failAt {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidSwap", "message": "Commit was at bafy"})
+				return
+			}
+
+			response := bsky.ApplyWritesResponse{
+				Commit: &bsky.CommitMeta{CID: cid.Sum(cid.CodecDagCBOR, []byte(fmt.Sprintf("commit %d", len(s.calls)))), Rev: fmt.Sprint(len(s.calls))},
+			}
+			if !s.noResults {
+				for _, write := range request.Writes {
+					result := bsky.WriteResult{Type: bsky.WriteResultTypeDelete}
+					if write.Type == bsky.WriteTypeCreate {
+						n := write.Value.(map[string]any)["n"]
+						result = bsky.WriteResult{Type: bsky.WriteResultTypeCreate, URI: fmt.Sprintf("at://test-did/app.bsky.feed.like/%v", n)}
+					}
+					response.Results = append(response.Results, result)
+				}
+			}
+			if s.extra {
+				response.Results = append(response.Results, bsky.WriteResult{Type: bsky.WriteResultTypeDelete})
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		}
+	}
+
+	newClient := func(t *testing.T, s *server) *client {
+		httpServer := httptest.NewServer(handler(s))
+		t.Cleanup(httpServer.Close)
+		return &client{
+			xrpcURL:    httpServer.URL,
+			session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+			httpClient: httpServer.Client(),
+		}
+	}
+
+	t.Run("Given more writes than a call accepts, When ApplyWrites is called, Then it should chain the calls and return the results in order", func(t *testing.T) {
+		s := &server{}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(450), WithSwapCommit(swapCommit), WithValidate(false))
+		require.NoError(t, err)
+
+		require.Len(t, s.calls, 3)
+		for i, size := range []int{200, 200, 50} {
+			assert.Len(t, s.calls[i].Writes, size)
+			assert.Equal(t, "test-did", s.calls[i].Repo)
+			assert.Equal(t, cid.Sum(cid.CodecDagCBOR, []byte(fmt.Sprintf("commit %d", i))).String(), s.calls[i].SwapCommit)
+			require.NotNil(t, s.calls[i].Validate)
+			assert.False(t, *s.calls[i].Validate)
+		}
+
+		require.Len(t, response.Results, 450)
+		assert.Equal(t, "at://test-did/app.bsky.feed.like/0", response.Results[0].URI)
+		assert.Equal(t, bsky.WriteResultTypeDelete, response.Results[201].Type)
+		assert.Equal(t, "at://test-did/app.bsky.feed.like/448", response.Results[448].URI)
+		assert.Equal(t, "3", response.Commit.Rev)
+	})
+
+	t.Run("Given a server sending no results, When ApplyWrites is called, Then it should return a result of the type of each write", func(t *testing.T) {
+		s := &server{noResults: true}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(2))
+		require.NoError(t, err)
+
+		assert.Equal(t, []bsky.WriteResult{{Type: bsky.WriteResultTypeCreate}, {Type: bsky.WriteResultTypeDelete}}, response.Results)
+		assert.Empty(t, s.calls[0].SwapCommit)
+		assert.Nil(t, s.calls[0].Validate)
+	})
+
+	t.Run("Given a call failing after another succeeded, When ApplyWrites is called, Then it should return the applied results and the error", func(t *testing.T) {
+		s := &server{failAt: 2}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(250), WithSwapCommit(swapCommit))
+
+		assert.ErrorIs(t, err, ErrInvalidSwap)
+		require.NotNil(t, response)
+		assert.Len(t, response.Results, 200)
+		assert.Equal(t, "1", response.Commit.Rev)
+	})
+
+	t.Run("Given the first call failing, When ApplyWrites is called, Then it should return only the error", func(t *testing.T) {
+		s := &server{failAt: 1}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(1))
+
+		assert.ErrorIs(t, err, ErrInvalidSwap)
+		assert.Nil(t, response)
+	})
+
+	t.Run("Given a server sending more results than writes, When ApplyWrites is called, Then it should return an error", func(t *testing.T) {
+		s := &server{extra: true}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(1))
+
+		assert.Equal(t, newError(http.StatusInternalServerError, "fail to decode apply writes response", "got 2 results for 1 writes"), err)
+		assert.Nil(t, response)
+	})
+
+	t.Run("Given a swap record option, When ApplyWrites is called, Then it should return an error without sending the request", func(t *testing.T) {
+		s := &server{}
+		response, err := newClient(t, s).ApplyWrites(context.Background(), writes(1), WithSwapRecord(swapCommit))
+
+		assert.Equal(t, newError(http.StatusBadRequest, "invalid swap record", "the writes of ApplyWrites are only checked against WithSwapCommit, not WithSwapRecord"), err)
+		assert.Nil(t, response)
+		assert.Empty(t, s.calls)
+	})
+
+	t.Run("Given no writes, When ApplyWrites is called, Then it should return an error", func(t *testing.T) {
+		response, err := newClient(t, &server{}).ApplyWrites(context.Background(), nil)
+
+		assert.Equal(t, newError(http.StatusBadRequest, "invalid writes", "writes must have at least one value"), err)
+		assert.Nil(t, response)
+	})
+}
+
+func TestClient_CreateRecord(t *testing.T) {
+	createdAt := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	type in struct {
+		collection string
+		record     any
+	}
+
+	type out struct {
+		record map[string]any
+		err    error
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given a typed record, When CreateRecord is called, Then it should send it with its $type",
+			in: in{
+				collection: bsky.CollectionFollow,
+				record:     bsky.FollowRecord{Subject: "did:plc:alice", CreatedAt: createdAt},
+			},
+			out: out{record: map[string]any{"$type": "app.bsky.graph.follow", "subject": "did:plc:alice", "createdAt": "2024-09-01T12:00:00Z"}},
+		},
+		{
+			name: "Given a record of a custom lexicon as a map, When CreateRecord is called, Then it should send it with its $type",
+			in: in{
+				collection: "com.example.status",
+				record:     map[string]any{"status": "online"},
+			},
+			out: out{record: map[string]any{"$type": "com.example.status", "status": "online"}},
+		},
+		{
+			name: "Given a record with a $type, When CreateRecord is called, Then it should keep it",
+			in: in{
+				collection: "com.example.status",
+				record:     map[string]any{"$type": "com.example.status#v2", "status": "online"},
+			},
+			out: out{record: map[string]any{"$type": "com.example.status#v2", "status": "online"}},
+		},
+		{
+			name: "Given a record that is not a JSON object, When CreateRecord is called, Then it should return an error",
+			in: in{
+				collection: "com.example.status",
+				record:     "online",
+			},
+			out: out{err: newError(http.StatusBadRequest, "fail to encode create record request", "record of com.example.status must encode to a JSON object")},
+		},
+		{
+			name: "Given a record that cannot be encoded, When CreateRecord is called, Then it should return an error",
+			in: in{
+				collection: "com.example.status",
+				record:     map[string]any{"status": make(chan int)},
+			},
+			out: out{err: newError(http.StatusBadRequest, "fail to encode create record request", "json: unsupported type: chan int")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.repo.createRecord", r.URL.Path)
+
+				var request map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				assert.Equal(t, map[string]any{"repo": "test-did", "collection": tt.in.collection, "rkey": "3l3qo2vutsw2b", "record": tt.out.record}, request)
+
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
+			}))
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.CreateRecord(context.Background(), tt.in.collection, tt.in.record, WithRKey("3l3qo2vutsw2b"))
+			if tt.out.err != nil {
+				assert.Equal(t, tt.out.err, err)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, &testRecordResponse, result)
+		})
+	}
+}
+
+func TestClient_createWrappers(t *testing.T) {
+	params := bsky.CreateRecordParams{Text: "test text", URI: "at://did:plc:alice/app.bsky.feed.post/3l3qo2vutsw2b", CID: "bafyreid"}
+	subject := map[string]any{"uri": params.URI, "cid": params.CID}
+
+	tests := []struct {
+		name   string
+		create func(c *client) (*bsky.CreateRecordResponse, error)
+		record map[string]any
+	}{
+		{
+			name: "Given a CreatePostRecord call, When the record is sent, Then it should hold only the post fields",
+			create: func(c *client) (*bsky.CreateRecordResponse, error) {
+				return c.CreatePostRecord(context.Background(), params)
+			},
+			record: map[string]any{"$type": "app.bsky.feed.post", "text": "test text"},
+		},
+		{
+			name: "Given a CreateLikeRecord call, When the record is sent, Then it should hold only the like fields",
+			create: func(c *client) (*bsky.CreateRecordResponse, error) {
+				return c.CreateLikeRecord(context.Background(), params)
+			},
+			record: map[string]any{"$type": "app.bsky.feed.like", "subject": subject},
+		},
+		{
+			name: "Given a CreateRepostRecord call, When the record is sent, Then it should hold only the repost fields",
+			create: func(c *client) (*bsky.CreateRecordResponse, error) {
+				return c.CreateRepostRecord(context.Background(), params)
+			},
+			record: map[string]any{"$type": "app.bsky.feed.repost", "subject": subject},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					Collection string         `json:"collection"`
+					Record     map[string]any `json:"record"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+				assert.Equal(t, tt.record["$type"], request.Collection)
+				assert.NotEmpty(t, request.Record["createdAt"])
+				delete(request.Record, "createdAt")
+				assert.Equal(t, tt.record, request.Record)
+
+				_ = json.NewEncoder(w).Encode(testRecordResponse)
+			}))
+			defer server.Close()
+
+			_, err := tt.create(&client{
+				xrpcURL:    server.URL,
+				session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+				httpClient: server.Client(),
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_invalidRKey(t *testing.T) {
+	lazuliClient := &client{
+		session:    &bsky.AuthResponse{AccessJwt: "test-token", DID: "test-did"},
+		httpClient: http.DefaultClient,
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		write   func() error
+		details string
+	}{
+		{
+			name: "Given an invalid rkey option, When CreateRecord is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.CreateRecord(ctx, bsky.CollectionPost, bsky.PostRecord{}, WithRKey("a/b"))
+				return err
+			},
+			details: `tid: invalid record key: "a/b" has invalid character '/'`,
+		},
+		{
+			name: "Given an empty rkey, When PutRecord is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.PutRecord(ctx, bsky.CollectionProfile, "", bsky.ProfileRecord{})
+				return err
+			},
+			details: `tid: invalid record key: "" must have between 1 and 512 characters`,
+		},
+		{
+			name: "Given an invalid rkey, When DeleteRecord is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.DeleteRecord(ctx, bsky.CollectionLike, "..")
+				return err
+			},
+			details: `tid: invalid record key: ".."`,
+		},
+		{
+			name: "Given an rkey option, When PutRecord is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.PutRecord(ctx, bsky.CollectionProfile, "self", bsky.ProfileRecord{}, WithRKey("other"))
+				return err
+			},
+			details: "the record key of PutRecord is its rkey argument, not WithRKey",
+		},
+		{
+			name: "Given an rkey option, When DeleteRecord is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.DeleteRecord(ctx, bsky.CollectionLike, "3l3qo2vutsw2b", WithRKey("other"))
+				return err
+			},
+			details: "the record key of DeleteRecord is its rkey argument, not WithRKey",
+		},
+		{
+			name: "Given a write with an invalid rkey, When ApplyWrites is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.ApplyWrites(ctx, []bsky.WriteOp{
+					bsky.NewCreateWriteOp(bsky.CollectionLike, bsky.LikeRecord{}),
+					bsky.NewDeleteWriteOp(bsky.CollectionLike, ""),
+				})
+				return err
+			},
+			details: `write 1: tid: invalid record key: "" must have between 1 and 512 characters`,
+		},
+		{
+			name: "Given an rkey option, When ApplyWrites is called, Then it should return an error without sending the request",
+			write: func() error {
+				_, err := lazuliClient.ApplyWrites(ctx, []bsky.WriteOp{bsky.NewDeleteWriteOp(bsky.CollectionLike, "3l3qo2vutsw2b")}, WithRKey("other"))
+				return err
+			},
+			details: "the record keys of ApplyWrites are those of its writes, not WithRKey",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, newError(http.StatusBadRequest, "invalid rkey", tt.details), tt.write())
+		})
+	}
+}
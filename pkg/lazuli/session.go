@@ -4,22 +4,54 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/identity"
 )
 
-func (c *client) CreateSession(ctx context.Context, identifier, password string) (*bsky.AuthResponse, error) {
+// CreateSessionOption configures optional fields of the request sent by CreateSession.
+type CreateSessionOption func(r *bsky.SessionRequest)
+
+// WithAuthFactorToken sends the code emailed to accounts with two-factor authentication enabled, after a previous
+// CreateSession call failed with ErrAuthFactorTokenRequired.
+func WithAuthFactorToken(token string) CreateSessionOption {
+	return func(r *bsky.SessionRequest) {
+		r.AuthFactorToken = token
+	}
+}
+
+// IdentityResolver resolves the handle or DID given to CreateSession to the identity of the account, including the PDS
+// hosting it. It is implemented by *identity.Directory.
+type IdentityResolver interface {
+	LookupHandle(ctx context.Context, handle string) (*identity.Identity, error)
+	LookupDID(ctx context.Context, did string) (*identity.Identity, error)
+}
+
+func (c *client) CreateSession(ctx context.Context, identifier, password string, opts ...CreateSessionOption) (*bsky.AuthResponse, error) {
 	request := bsky.SessionRequest{
 		Identifier: identifier,
 		Password:   password,
 	}
+	for _, opt := range opts {
+		opt(&request)
+	}
 	requestBody, _ := json.Marshal(request)
 
-	reqURL := fmt.Sprintf("%s/com.atproto.server.createSession", c.xrpcURL)
+	account, err := c.resolveAccount(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	xrpcURL := c.xrpcURL
+	if account != nil {
+		xrpcURL = account.PDSEndpoint() + "/xrpc"
+	}
+	reqURL := fmt.Sprintf("%s/com.atproto.server.createSession", xrpcURL)
 
-	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(requestBody))
+	req, err := c.newRequest(ctx, "POST", reqURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, newError(http.StatusInternalServerError, "fail to create session request struct", err.Error())
 	}
@@ -40,8 +72,377 @@ func (c *client) CreateSession(ctx context.Context, identifier, password string)
 	if jsonDecoderErr := json.NewDecoder(resp.Body).Decode(&didResponse); jsonDecoderErr != nil {
 		return nil, newError(http.StatusInternalServerError, "error to decode json", jsonDecoderErr.Error())
 	}
+	// the document is optional in the response, so the resolved one keeps the session calls on the PDS logged in to
+	if account != nil && didResponse.DIDDoc.PDSEndpoint() == "" && account.DID == didResponse.DID {
+		didResponse.DIDDoc = *account.Doc
+	}
 
-	c.session = &didResponse
+	if err := c.saveSession(ctx, &didResponse); err != nil {
+		return nil, err
+	}
 
 	return &didResponse, nil
 }
+
+// ResumeSession makes the client use a previously saved session, validating it with com.atproto.server.getSession.
+// The tokens are refreshed when the access token has expired, and the account fields of the session are updated with
+// the current ones. When the session is not valid anymore, the client keeps the session it had before.
+func (c *client) ResumeSession(ctx context.Context, session *bsky.AuthResponse) (*bsky.AuthResponse, error) {
+	if session == nil {
+		return nil, errNoSession()
+	}
+
+	previous := c.currentSession()
+	resumed := *session
+	c.setSession(&resumed)
+
+	info, err := c.GetSession(ctx)
+	if err != nil {
+		c.setSession(previous)
+		return nil, err
+	}
+	if info.DID != session.DID {
+		c.setSession(previous)
+		return nil, newError(http.StatusUnauthorized, "fail to resume session", fmt.Sprintf("session belongs to %s instead of %s", info.DID, session.DID))
+	}
+
+	// GetSession may have refreshed the tokens, so the account fields are applied over the current session.
+	current := *c.currentSession()
+	current.Handle = info.Handle
+	current.DIDDoc = info.DIDDoc
+	current.Email = info.Email
+	current.EmailConfirmed = info.EmailConfirmed
+	current.EmailAuthFactor = info.EmailAuthFactor
+	current.Active = info.Active
+	current.Status = info.Status
+
+	if err := c.saveSession(ctx, &current); err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+// GetSession returns the status of the account of the current session.
+func (c *client) GetSession(ctx context.Context) (*bsky.SessionResponse, error) {
+	var sessionResponse bsky.SessionResponse
+	err := c.withSessionRefresh(ctx, func(sess *bsky.AuthResponse) error {
+		reqURL := fmt.Sprintf("%s/com.atproto.server.getSession", c.serviceURL(sess))
+		req, err := c.newRequest(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return newError(http.StatusInternalServerError, "fail to create get session request struct", err.Error())
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sess.AccessJwt))
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return newError(http.StatusInternalServerError, "error to get session", err.Error())
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return newErrorFromResponse(resp, "get session request failed")
+		}
+
+		if jsonDecoderErr := json.NewDecoder(resp.Body).Decode(&sessionResponse); jsonDecoderErr != nil {
+			return newError(http.StatusInternalServerError, "error to decode json", jsonDecoderErr.Error())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sessionResponse, nil
+}
+
+// DeleteSession revokes the refresh token of the current session, and removes the session from the client and from
+// its SessionStore.
+func (c *client) DeleteSession(ctx context.Context) error {
+	sess := c.currentSession()
+	if sess == nil {
+		return c.missingSession()
+	}
+
+	reqURL := fmt.Sprintf("%s/com.atproto.server.deleteSession", c.serviceURL(sess))
+	req, err := c.newRequest(ctx, "POST", reqURL, nil)
+	if err != nil {
+		return newError(http.StatusInternalServerError, "fail to create delete session request struct", err.Error())
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sess.RefreshJwt))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return newError(http.StatusInternalServerError, "error to delete session", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newErrorFromResponse(resp, "delete session request failed")
+	}
+
+	c.setSession(nil)
+	if c.store != nil {
+		if err := c.store.Delete(ctx); err != nil {
+			return newError(http.StatusInternalServerError, "fail to delete stored session", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// RefreshSession exchanges the refresh token of the current session for a new pair of tokens and stores the new
+// session on the client.
+func (c *client) RefreshSession(ctx context.Context) (*bsky.AuthResponse, error) {
+	return c.refreshSession(ctx, c.currentSession())
+}
+
+// LoadSession makes the client use the session of its SessionStore and returns it. It returns nil when the store has
+// no session or the client has no store, and an error when the store cannot be read, such as a corrupted file.
+func (c *client) LoadSession(ctx context.Context) (*bsky.AuthResponse, error) {
+	if c.store == nil {
+		return nil, nil
+	}
+
+	session, err := c.store.Load(ctx)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		c.sessionMu.Lock()
+		c.loadErr = err
+		c.sessionMu.Unlock()
+		return nil, newError(http.StatusInternalServerError, "fail to load stored session", err.Error())
+	}
+
+	c.setSession(session)
+	return session, nil
+}
+
+// Session returns the session currently used by the client, or nil when there is none.
+func (c *client) Session() *bsky.AuthResponse {
+	return c.currentSession()
+}
+
+// sessionRefresh is a refresh request in flight, shared by every caller that needs a new session at the same time.
+type sessionRefresh struct {
+	stale   *bsky.AuthResponse
+	done    chan struct{}
+	session *bsky.AuthResponse
+	err     error
+}
+
+func (c *client) currentSession() *bsky.AuthResponse {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.session
+}
+
+func (c *client) setSession(session *bsky.AuthResponse) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.session = session
+	c.loadErr = nil
+}
+
+// swapSession sets the session used by the client to session only when it is still old.
+func (c *client) swapSession(old, session *bsky.AuthResponse) bool {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.session != old {
+		return false
+	}
+	c.session = session
+	c.loadErr = nil
+	return true
+}
+
+// saveSession sets the session used by the client and persists it when a SessionStore is configured.
+func (c *client) saveSession(ctx context.Context, session *bsky.AuthResponse) error {
+	c.setSession(session)
+	return c.storeSession(ctx, session)
+}
+
+// storeSession persists session when a SessionStore is configured.
+func (c *client) storeSession(ctx context.Context, session *bsky.AuthResponse) error {
+	if c.store == nil {
+		return nil
+	}
+	if err := c.store.Save(ctx, session); err != nil {
+		return newError(http.StatusInternalServerError, "fail to save session", err.Error())
+	}
+	return nil
+}
+
+// serviceURL returns the XRPC URL of the PDS declared by the DID document of sess, so the calls of an account hosted
+// behind an entryway reach its own PDS. It falls back to the URL given to NewClient when there is none.
+func (c *client) serviceURL(sess *bsky.AuthResponse) string {
+	if sess != nil {
+		if pds := sess.DIDDoc.PDSEndpoint(); pds != "" {
+			return pds + "/xrpc"
+		}
+	}
+	return c.xrpcURL
+}
+
+// accountURL returns the XRPC URL of the PDS hosting the account of identifier, where its session is created and its
+// repository is read. When an IdentityResolver is configured, handles and DIDs are resolved to that PDS; otherwise,
+// and always for emails, the URL given to NewClient is returned.
+func (c *client) accountURL(ctx context.Context, identifier string) (string, error) {
+	account, err := c.resolveAccount(ctx, identifier)
+	if err != nil || account == nil {
+		return c.xrpcURL, err
+	}
+	return account.PDSEndpoint() + "/xrpc", nil
+}
+
+// resolveAccount returns the identity of the account of identifier, which has a PDS endpoint, or nil when no
+// IdentityResolver is configured or identifier is an email.
+func (c *client) resolveAccount(ctx context.Context, identifier string) (*identity.Identity, error) {
+	if c.identities == nil || strings.Contains(strings.TrimPrefix(identifier, "@"), "@") {
+		return nil, nil
+	}
+
+	var ident *identity.Identity
+	var err error
+	if strings.HasPrefix(identifier, "did:") {
+		ident, err = c.identities.LookupDID(ctx, identifier)
+	} else {
+		ident, err = c.identities.LookupHandle(ctx, identifier)
+	}
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "fail to resolve identifier", err.Error())
+	}
+
+	if ident.PDSEndpoint() == "" {
+		return nil, newError(http.StatusBadRequest, "fail to resolve identifier", fmt.Sprintf("%s has no pds endpoint", ident.DID))
+	}
+	return ident, nil
+}
+
+func errNoSession() *Error {
+	return newError(http.StatusUnauthorized, "no active session", "create a session before calling authenticated endpoints")
+}
+
+// missingSession returns the error of a call needing a session when the client has none. When NewClient failed to
+// load the stored session, that failure is returned instead, so it is not mistaken for an empty store.
+func (c *client) missingSession() *Error {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	if c.loadErr != nil {
+		return newError(http.StatusInternalServerError, "fail to load stored session", c.loadErr.Error())
+	}
+	return errNoSession()
+}
+
+// withSessionRefresh runs fn with the current session and, when it fails because the access token has expired,
+// refreshes the session and runs fn once more with the new one.
+func (c *client) withSessionRefresh(ctx context.Context, fn func(sess *bsky.AuthResponse) error) error {
+	sess := c.currentSession()
+	if sess == nil {
+		return c.missingSession()
+	}
+
+	err := fn(sess)
+	if !errors.Is(err, ErrExpiredToken) {
+		return err
+	}
+
+	refreshed, refreshErr := c.refreshSession(ctx, sess)
+	if refreshErr != nil {
+		return refreshErr
+	}
+
+	return fn(refreshed)
+}
+
+// refreshSession replaces the stale session with a refreshed one. Concurrent callers refreshing the same session share
+// a single request to the PDS, and when the stored session has already been swapped by another caller it is returned
+// as is.
+func (c *client) refreshSession(ctx context.Context, stale *bsky.AuthResponse) (*bsky.AuthResponse, error) {
+	if stale == nil {
+		return nil, c.missingSession()
+	}
+
+	c.refreshMu.Lock()
+	if current := c.currentSession(); current != stale {
+		c.refreshMu.Unlock()
+		if current == nil {
+			return nil, errNoSession()
+		}
+		return current, nil
+	}
+	call := c.refreshing
+	// a refresh still in flight for a session replaced since then, such as by CreateSession, cannot refresh this one
+	if call == nil || call.stale != stale {
+		call = &sessionRefresh{stale: stale, done: make(chan struct{})}
+		c.refreshing = call
+		// the refresh is shared by every waiting caller, so it is not cancelled with the context of the caller that
+		// started it; the timeout of the HTTP client still bounds it
+		go c.runRefresh(context.WithoutCancel(ctx), call, stale)
+	}
+	c.refreshMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.session, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runRefresh refreshes the stale session and stores the new one, unless the session was deleted or replaced while
+// the request was in flight.
+func (c *client) runRefresh(ctx context.Context, call *sessionRefresh, stale *bsky.AuthResponse) {
+	session, err := c.requestRefreshSession(ctx, stale)
+	if err == nil {
+		if c.swapSession(stale, session) {
+			err = c.storeSession(ctx, session)
+		} else if session = c.currentSession(); session == nil {
+			err = errNoSession()
+		}
+	}
+	if err != nil {
+		session = nil
+	}
+
+	c.refreshMu.Lock()
+	if c.refreshing == call {
+		c.refreshing = nil
+	}
+	call.session, call.err = session, err
+	c.refreshMu.Unlock()
+	close(call.done)
+}
+
+func (c *client) requestRefreshSession(ctx context.Context, stale *bsky.AuthResponse) (*bsky.AuthResponse, error) {
+	reqURL := fmt.Sprintf("%s/com.atproto.server.refreshSession", c.serviceURL(stale))
+
+	req, err := c.newRequest(ctx, "POST", reqURL, nil)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to create refresh session request struct", err.Error())
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", stale.RefreshJwt))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "error to refresh session", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(resp, "refresh session request failed")
+	}
+
+	// refreshSession does not return the email fields, so they are kept from the stale session.
+	refreshed := *stale
+	if jsonDecoderErr := json.NewDecoder(resp.Body).Decode(&refreshed); jsonDecoderErr != nil {
+		return nil, newError(http.StatusInternalServerError, "error to decode json", jsonDecoderErr.Error())
+	}
+
+	return &refreshed, nil
+}
@@ -0,0 +1,127 @@
+package lazuli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+)
+
+// ErrSessionNotFound is returned by a SessionStore when there is no session stored.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore persists the session of a client, so it can be resumed after a restart instead of creating a new one.
+type SessionStore interface {
+	Load(ctx context.Context) (*bsky.AuthResponse, error)
+	Save(ctx context.Context, session *bsky.AuthResponse) error
+	Delete(ctx context.Context) error
+}
+
+// MemorySessionStore keeps the session in memory, which is mostly useful for tests and short-lived processes.
+type MemorySessionStore struct {
+	mu      sync.RWMutex
+	session *bsky.AuthResponse
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{}
+}
+
+func (s *MemorySessionStore) Load(_ context.Context) (*bsky.AuthResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.session == nil {
+		return nil, ErrSessionNotFound
+	}
+	session := *s.session
+	return &session, nil
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, session *bsky.AuthResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *session
+	s.session = &stored
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = nil
+	return nil
+}
+
+// FileSessionStore keeps the session as JSON in a file readable only by the current user. Writes go to a temporary
+// file that is renamed over the previous one, so a crash never leaves a truncated session behind.
+type FileSessionStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{path: path}
+}
+
+func (s *FileSessionStore) Load(_ context.Context) (*bsky.AuthResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	var session bsky.AuthResponse
+	if err := json.Unmarshal(b, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *FileSessionStore) Save(_ context.Context, session *bsky.AuthResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b)
+}
+
+func (s *FileSessionStore) Delete(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file, readable only by the current user, that is renamed over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
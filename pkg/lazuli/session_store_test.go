@@ -0,0 +1,90 @@
+package lazuli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) SessionStore
+	}{
+		{
+			name: "Given a MemorySessionStore",
+			store: func(t *testing.T) SessionStore {
+				return NewMemorySessionStore()
+			},
+		},
+		{
+			name: "Given a FileSessionStore",
+			store: func(t *testing.T) SessionStore {
+				return NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+", When nothing was saved, Then Load should return ErrSessionNotFound", func(t *testing.T) {
+			store := tt.store(t)
+
+			session, err := store.Load(context.Background())
+
+			assert.Nil(t, session)
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+		})
+
+		t.Run(tt.name+", When a session is saved, Then Load should return it", func(t *testing.T) {
+			store := tt.store(t)
+			saved := &bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"}
+
+			require.NoError(t, store.Save(context.Background(), saved))
+			session, err := store.Load(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, saved, session)
+		})
+
+		t.Run(tt.name+", When the session is deleted, Then Load should return ErrSessionNotFound", func(t *testing.T) {
+			store := tt.store(t)
+
+			require.NoError(t, store.Save(context.Background(), &bsky.AuthResponse{DID: "test-did"}))
+			require.NoError(t, store.Delete(context.Background()))
+			session, err := store.Load(context.Background())
+
+			assert.Nil(t, session)
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+			assert.NoError(t, store.Delete(context.Background()))
+		})
+	}
+}
+
+func TestFileSessionStore_Save(t *testing.T) {
+	t.Run("Given a FileSessionStore, When a session is saved, Then the file should only be readable by the owner", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		store := NewFileSessionStore(path)
+
+		require.NoError(t, store.Save(context.Background(), &bsky.AuthResponse{DID: "test-did"}))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Given a corrupted session file, When Load is called, Then it should return an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		session, err := NewFileSessionStore(path).Load(context.Background())
+
+		assert.Nil(t, session)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrSessionNotFound)
+	})
+}
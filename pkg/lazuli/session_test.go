@@ -3,12 +3,18 @@ package lazuli
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/identity"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClient_CreateSession(t *testing.T) {
@@ -132,3 +138,722 @@ func TestClient_CreateSession(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_RefreshSession(t *testing.T) {
+	type in struct {
+		ctx     context.Context
+		session *bsky.AuthResponse
+	}
+
+	type out struct {
+		authResponse *bsky.AuthResponse
+		err          error
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a stored session, When RefreshSession is called, Then it should swap the tokens and keep the remaining fields",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{DID: "test-did", Email: "test@test.com", AccessJwt: "old-access", RefreshJwt: "old-refresh"},
+			},
+			out: out{
+				authResponse: &bsky.AuthResponse{DID: "test-did", Email: "test@test.com", AccessJwt: "new-access", RefreshJwt: "new-refresh"},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.server.refreshSession", r.URL.Path)
+				assert.Equal(t, "Bearer old-refresh", r.Header.Get("Authorization"))
+				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(map[string]string{"did": "test-did", "accessJwt": "new-access", "refreshJwt": "new-refresh"})
+			},
+		},
+		{
+			name: "Given an expired refresh token, When RefreshSession is called, Then it should return an error",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{AccessJwt: "old-access", RefreshJwt: "old-refresh"},
+			},
+			out: out{
+				err: newError(http.StatusBadRequest, "refresh session request failed", `{"error":"ExpiredToken"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+			},
+		},
+		{
+			name: "Given no session, When RefreshSession is called, Then it should return a no active session error",
+			in: in{
+				ctx: context.Background(),
+			},
+			out: out{
+				err: errNoSession(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    tt.in.session,
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.RefreshSession(tt.in.ctx)
+
+			if tt.out.err != nil {
+				assert.Nil(t, result)
+				assert.Equal(t, tt.out.err, err)
+				assert.Equal(t, tt.in.session, lazuliClient.currentSession())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.authResponse, result)
+				assert.Equal(t, tt.out.authResponse, lazuliClient.currentSession())
+			}
+		})
+	}
+}
+
+func TestClient_withSessionRefresh(t *testing.T) {
+	t.Run("Given concurrent requests with an expired token, When they fail, Then a single refresh should be shared and each request retried", func(t *testing.T) {
+		var refreshCalls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/com.atproto.server.refreshSession":
+				refreshCalls.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				_ = json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-access", "refreshJwt": "new-refresh"})
+			case "/app.bsky.feed.getPosts":
+				if r.Header.Get("Authorization") != "Bearer new-access" {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has expired"})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(bsky.PostResponse{Posts: bsky.Posts{{URI: "test-uri"}}})
+			}
+		}))
+		defer server.Close()
+
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			session:    &bsky.AuthResponse{AccessJwt: "old-access", RefreshJwt: "old-refresh"},
+			httpClient: server.Client(),
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				posts, err := lazuliClient.GetPosts(context.Background(), "test-uri")
+				assert.NoError(t, err)
+				assert.Equal(t, bsky.Posts{{URI: "test-uri"}}, posts)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), refreshCalls.Load())
+		assert.Equal(t, "new-access", lazuliClient.currentSession().AccessJwt)
+	})
+
+	t.Run("Given a session deleted while a request fails with an expired token, When it is refreshed, Then it should return a no session error", func(t *testing.T) {
+		refreshStarted := make(chan struct{})
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/com.atproto.server.refreshSession":
+				close(refreshStarted)
+				<-release
+				_ = json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-access", "refreshJwt": "new-refresh"})
+			case "/app.bsky.feed.getPosts":
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has expired"})
+			}
+		}))
+		defer server.Close()
+
+		store := NewMemorySessionStore()
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			session:    &bsky.AuthResponse{AccessJwt: "old-access", RefreshJwt: "old-refresh"},
+			httpClient: server.Client(),
+			store:      store,
+		}
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := lazuliClient.GetPosts(context.Background(), "test-uri")
+			errs <- err
+		}()
+
+		<-refreshStarted
+		assert.NoError(t, lazuliClient.DeleteSession(context.Background()))
+		close(release)
+
+		assert.Equal(t, errNoSession(), <-errs)
+		assert.Nil(t, lazuliClient.currentSession())
+		_, err := store.Load(context.Background())
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+	})
+
+	t.Run("Given a session deleted before the refresh, When it is refreshed, Then it should return a no session error", func(t *testing.T) {
+		lazuliClient := &client{}
+
+		refreshed, err := lazuliClient.refreshSession(context.Background(), &bsky.AuthResponse{RefreshJwt: "old-refresh"})
+
+		assert.Equal(t, errNoSession(), err)
+		assert.Nil(t, refreshed)
+	})
+
+	t.Run("Given the caller starting a refresh is cancelled, When another caller waits on it, Then the refresh should still complete for it", func(t *testing.T) {
+		refreshStarted := make(chan struct{})
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(refreshStarted)
+			<-release
+			_ = json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-access", "refreshJwt": "new-refresh"})
+		}))
+		defer server.Close()
+
+		stale := &bsky.AuthResponse{AccessJwt: "old-access", RefreshJwt: "old-refresh"}
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			session:    stale,
+			httpClient: server.Client(),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		firstErr := make(chan error, 1)
+		go func() {
+			_, err := lazuliClient.refreshSession(ctx, stale)
+			firstErr <- err
+		}()
+		<-refreshStarted
+
+		type result struct {
+			session *bsky.AuthResponse
+			err     error
+		}
+		second := make(chan result, 1)
+		go func() {
+			session, err := lazuliClient.refreshSession(context.Background(), stale)
+			second <- result{session: session, err: err}
+		}()
+
+		cancel()
+		assert.ErrorIs(t, <-firstErr, context.Canceled)
+		close(release)
+
+		got := <-second
+		assert.NoError(t, got.err)
+		assert.Equal(t, "new-access", got.session.AccessJwt)
+		assert.Equal(t, "new-access", lazuliClient.currentSession().AccessJwt)
+	})
+
+	t.Run("Given a refresh in flight for a replaced session, When the new session is refreshed, Then it should not join that refresh", func(t *testing.T) {
+		refreshStarted := make(chan struct{})
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer old-refresh" {
+				close(refreshStarted)
+				<-release
+				_ = json.NewEncoder(w).Encode(map[string]string{"accessJwt": "old-new-access", "refreshJwt": "old-new-refresh"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-access", "refreshJwt": "new-refresh"})
+		}))
+		defer server.Close()
+		// the blocked refresh is released when the test fails before releasing it, so closing the server does not hang
+		defer func() {
+			select {
+			case <-release:
+			default:
+				close(release)
+			}
+		}()
+
+		stale := &bsky.AuthResponse{AccessJwt: "old-access", RefreshJwt: "old-refresh"}
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			session:    stale,
+			httpClient: server.Client(),
+		}
+
+		firstErr := make(chan error, 1)
+		go func() {
+			_, err := lazuliClient.refreshSession(context.Background(), stale)
+			firstErr <- err
+		}()
+		<-refreshStarted
+
+		replaced := &bsky.AuthResponse{AccessJwt: "other-access", RefreshJwt: "other-refresh"}
+		require.True(t, lazuliClient.swapSession(stale, replaced))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		refreshed, err := lazuliClient.refreshSession(ctx, replaced)
+		require.NoError(t, err)
+		assert.Equal(t, "new-access", refreshed.AccessJwt)
+
+		close(release)
+		assert.NoError(t, <-firstErr)
+		assert.Equal(t, "new-access", lazuliClient.currentSession().AccessJwt)
+	})
+
+	t.Run("Given a request failing with another error, When it runs, Then it should not refresh the session", func(t *testing.T) {
+		lazuliClient := &client{session: &bsky.AuthResponse{AccessJwt: "access"}}
+		calls := 0
+
+		err := lazuliClient.withSessionRefresh(context.Background(), func(sess *bsky.AuthResponse) error {
+			calls++
+			return newError(http.StatusBadRequest, "request failed", `{"error":"InvalidRequest"}`)
+		})
+
+		assert.Equal(t, newError(http.StatusBadRequest, "request failed", `{"error":"InvalidRequest"}`), err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestClient_saveSession(t *testing.T) {
+	t.Run("Given a session store, When a session is created and refreshed, Then every session should be persisted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/com.atproto.server.createSession":
+				_ = json.NewEncoder(w).Encode(bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"})
+			case "/com.atproto.server.refreshSession":
+				_ = json.NewEncoder(w).Encode(bsky.AuthResponse{DID: "test-did", AccessJwt: "new-access", RefreshJwt: "new-refresh"})
+			}
+		}))
+		defer server.Close()
+
+		store := NewMemorySessionStore()
+		lazuliClient := &client{
+			xrpcURL:    server.URL,
+			httpClient: server.Client(),
+			store:      store,
+		}
+
+		created, err := lazuliClient.CreateSession(context.Background(), "test-user", "test-password")
+		assert.NoError(t, err)
+		stored, _ := store.Load(context.Background())
+		assert.Equal(t, created, stored)
+
+		refreshed, err := lazuliClient.RefreshSession(context.Background())
+		assert.NoError(t, err)
+		stored, _ = store.Load(context.Background())
+		assert.Equal(t, refreshed, stored)
+		assert.Equal(t, "new-refresh", stored.RefreshJwt)
+	})
+}
+
+func TestClient_ResumeSession(t *testing.T) {
+	type in struct {
+		ctx     context.Context
+		session *bsky.AuthResponse
+	}
+
+	type out struct {
+		authResponse *bsky.AuthResponse
+		err          error
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a valid saved session, When ResumeSession is called, Then it should use it with the current account fields",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{DID: "test-did", Handle: "old.handle", AccessJwt: "access", RefreshJwt: "refresh"},
+			},
+			out: out{
+				authResponse: &bsky.AuthResponse{DID: "test-did", Handle: "new.handle", AccessJwt: "access", RefreshJwt: "refresh", Active: true},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+				_ = json.NewEncoder(w).Encode(bsky.SessionResponse{DID: "test-did", Handle: "new.handle", Active: true})
+			},
+		},
+		{
+			name: "Given a saved session with an expired access token, When ResumeSession is called, Then it should refresh the tokens",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"},
+			},
+			out: out{
+				authResponse: &bsky.AuthResponse{DID: "test-did", AccessJwt: "new-access", RefreshJwt: "new-refresh", Active: true},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/com.atproto.server.refreshSession" {
+					_ = json.NewEncoder(w).Encode(bsky.AuthResponse{DID: "test-did", AccessJwt: "new-access", RefreshJwt: "new-refresh"})
+					return
+				}
+				if r.Header.Get("Authorization") != "Bearer new-access" {
+					w.WriteHeader(http.StatusBadRequest)
+					_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(bsky.SessionResponse{DID: "test-did", Active: true})
+			},
+		},
+		{
+			name: "Given a revoked session, When ResumeSession is called, Then it should return an error",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"},
+			},
+			out: out{
+				err: newError(http.StatusUnauthorized, "get session request failed", `{"error":"InvalidToken"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidToken"})
+			},
+		},
+		{
+			name: "Given a session of another account, When ResumeSession is called, Then it should return an error",
+			in: in{
+				ctx:     context.Background(),
+				session: &bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"},
+			},
+			out: out{
+				err: newError(http.StatusUnauthorized, "fail to resume session", "session belongs to other-did instead of test-did"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(bsky.SessionResponse{DID: "other-did"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.ResumeSession(tt.in.ctx, tt.in.session)
+
+			if tt.out.err != nil {
+				assert.Nil(t, result)
+				assert.Equal(t, tt.out.err, err)
+				assert.Nil(t, lazuliClient.Session())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.authResponse, result)
+				assert.Equal(t, tt.out.authResponse, lazuliClient.Session())
+			}
+		})
+	}
+}
+
+func TestClient_GetSession(t *testing.T) {
+	type out struct {
+		sessionResponse *bsky.SessionResponse
+		err             error
+	}
+
+	tests := []struct {
+		name    string
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given an active session, When GetSession is called, Then it should return the account status",
+			out: out{
+				sessionResponse: &bsky.SessionResponse{DID: "test-did", Handle: "test.handle", EmailConfirmed: true, Active: false, Status: "deactivated"},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.server.getSession", r.URL.Path)
+				_ = json.NewEncoder(w).Encode(bsky.SessionResponse{DID: "test-did", Handle: "test.handle", EmailConfirmed: true, Active: false, Status: "deactivated"})
+			},
+		},
+		{
+			name: "Given an invalid response, When GetSession is called, Then it should return a decode error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "error to decode json", "unexpected EOF"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    &bsky.AuthResponse{DID: "test-did", AccessJwt: "access"},
+				httpClient: server.Client(),
+			}
+
+			result, err := lazuliClient.GetSession(context.Background())
+
+			if tt.out.err != nil {
+				assert.Nil(t, result)
+				assert.Equal(t, tt.out.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out.sessionResponse, result)
+			}
+		})
+	}
+}
+
+func TestClient_DeleteSession(t *testing.T) {
+	type out struct {
+		err error
+	}
+
+	tests := []struct {
+		name    string
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given an active session, When DeleteSession is called, Then it should revoke it and remove it from the store",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.server.deleteSession", r.URL.Path)
+				assert.Equal(t, "Bearer refresh", r.Header.Get("Authorization"))
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			name: "Given a revoked session, When DeleteSession is called, Then it should return an error and keep the session",
+			out: out{
+				err: newError(http.StatusUnauthorized, "delete session request failed", `{"error":"ExpiredToken"}`+"\n"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			session := &bsky.AuthResponse{DID: "test-did", AccessJwt: "access", RefreshJwt: "refresh"}
+			store := NewMemorySessionStore()
+			_ = store.Save(context.Background(), session)
+
+			lazuliClient := &client{
+				xrpcURL:    server.URL,
+				session:    session,
+				store:      store,
+				httpClient: server.Client(),
+			}
+
+			err := lazuliClient.DeleteSession(context.Background())
+			stored, _ := store.Load(context.Background())
+
+			if tt.out.err != nil {
+				assert.Equal(t, tt.out.err, err)
+				assert.Equal(t, session, lazuliClient.Session())
+				assert.Equal(t, session, stored)
+			} else {
+				assert.NoError(t, err)
+				assert.Nil(t, lazuliClient.Session())
+				assert.Nil(t, stored)
+			}
+		})
+	}
+}
+
+func TestClient_CreateSession_withAuthFactorToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request bsky.SessionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.AuthFactorToken != "123456" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "AuthFactorTokenRequired", "message": "A sign in code has been sent to your email address"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(bsky.AuthResponse{AccessJwt: "valid-token", EmailAuthFactor: true})
+	}))
+	defer server.Close()
+
+	lazuliClient := &client{
+		xrpcURL:    server.URL,
+		httpClient: server.Client(),
+	}
+
+	t.Run("Given an account with email two-factor enabled, When CreateSession is called without a token, Then it should return ErrAuthFactorTokenRequired", func(t *testing.T) {
+		result, err := lazuliClient.CreateSession(context.Background(), "test-user", "test-password")
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrAuthFactorTokenRequired)
+	})
+
+	t.Run("Given an account with email two-factor enabled, When CreateSession is called with the emailed token, Then it should create the session", func(t *testing.T) {
+		result, err := lazuliClient.CreateSession(context.Background(), "test-user", "test-password", WithAuthFactorToken("123456"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &bsky.AuthResponse{AccessJwt: "valid-token", EmailAuthFactor: true}, result)
+	})
+}
+
+// identityResolverMock resolves the handles and DIDs it holds, and returns identity.ErrHandleNotFound for any other.
+type identityResolverMock map[string]*identity.Identity
+
+func (m identityResolverMock) LookupHandle(_ context.Context, handle string) (*identity.Identity, error) {
+	if ident, ok := m[handle]; ok {
+		return ident, nil
+	}
+	return nil, fmt.Errorf("%w: %s", identity.ErrHandleNotFound, handle)
+}
+
+func (m identityResolverMock) LookupDID(ctx context.Context, did string) (*identity.Identity, error) {
+	return m.LookupHandle(ctx, did)
+}
+
+func pdsDoc(did, endpoint string) bsky.DIDDoc {
+	return bsky.DIDDoc{
+		ID:      did,
+		Service: []bsky.DIDService{{ID: "#atproto_pds", Type: bsky.DIDServiceTypePDS, ServiceEndpoint: endpoint}},
+	}
+}
+
+func TestClient_pdsDiscovery(t *testing.T) {
+	var pdsPaths []string
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pdsPaths = append(pdsPaths, r.URL.Path)
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			_ = json.NewEncoder(w).Encode(bsky.AuthResponse{DID: "did:plc:alice", AccessJwt: "pds-token"})
+		case "/xrpc/com.atproto.server.getSession":
+			_ = json.NewEncoder(w).Encode(bsky.SessionResponse{DID: "did:plc:alice", Handle: "alice.test"})
+		case "/xrpc/com.atproto.repo.createRecord":
+			_ = json.NewEncoder(w).Encode(testRecordResponse)
+		case "/xrpc/com.atproto.sync.getRepo":
+			_, _ = w.Write([]byte("car"))
+		case "/xrpc/com.atproto.repo.listRecords":
+			_ = json.NewEncoder(w).Encode(bsky.ListRecordsResponse{})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer pds.Close()
+
+	var entrywayPaths []string
+	entryway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entrywayPaths = append(entrywayPaths, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(bsky.AuthResponse{
+			DID:       "did:plc:alice",
+			AccessJwt: "entryway-token",
+			DIDDoc:    pdsDoc("did:plc:alice", pds.URL+"/"),
+		})
+	}))
+	defer entryway.Close()
+
+	doc := pdsDoc("did:plc:alice", pds.URL)
+	ident := &identity.Identity{DID: "did:plc:alice", Handle: "alice.test", Doc: &doc}
+	resolver := identityResolverMock{
+		"alice.test":    ident,
+		"did:plc:alice": ident,
+		"bob.test":      {DID: "did:plc:bob", Handle: "bob.test", Doc: &bsky.DIDDoc{ID: "did:plc:bob"}},
+	}
+
+	t.Run("Given a session whose DID document declares a PDS, When repo calls are made, Then they should be sent to the PDS", func(t *testing.T) {
+		pdsPaths, entrywayPaths = nil, nil
+		lazuliClient := NewClient(entryway.URL, "")
+
+		_, err := lazuliClient.CreateSession(context.Background(), "alice.test", "password")
+		assert.NoError(t, err)
+		_, err = lazuliClient.CreatePostRecord(context.Background(), bsky.CreateRecordParams{Text: "hello"})
+		assert.NoError(t, err)
+		_, err = lazuliClient.GetSession(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"/com.atproto.server.createSession"}, entrywayPaths)
+		assert.Equal(t, []string{"/xrpc/com.atproto.repo.createRecord", "/xrpc/com.atproto.server.getSession"}, pdsPaths)
+	})
+
+	t.Run("Given a resumed session whose DID document declares a PDS, When it is validated, Then it should be sent to the PDS", func(t *testing.T) {
+		pdsPaths, entrywayPaths = nil, nil
+		lazuliClient := NewClient(entryway.URL, "")
+
+		_, err := lazuliClient.ResumeSession(context.Background(), &bsky.AuthResponse{DID: "did:plc:alice", DIDDoc: pdsDoc("did:plc:alice", pds.URL)})
+		assert.NoError(t, err)
+
+		assert.Empty(t, entrywayPaths)
+		assert.Equal(t, []string{"/xrpc/com.atproto.server.getSession"}, pdsPaths)
+	})
+
+	t.Run("Given an identity resolver, When CreateSession is called with a handle or a DID, Then it should log in and write at the PDS of the account", func(t *testing.T) {
+		for _, identifier := range []string{"alice.test", "did:plc:alice"} {
+			pdsPaths = nil
+			lazuliClient := NewClient("", "", WithIdentityResolver(resolver))
+
+			result, err := lazuliClient.CreateSession(context.Background(), identifier, "password")
+			assert.NoError(t, err)
+			assert.Equal(t, "pds-token", result.AccessJwt)
+			assert.Equal(t, doc, result.DIDDoc)
+
+			_, err = lazuliClient.CreatePostRecord(context.Background(), bsky.CreateRecordParams{Text: "hello"})
+			assert.NoError(t, err)
+			_, err = lazuliClient.ListRecords(context.Background(), "did:plc:alice", bsky.CollectionPost, "", 0)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{
+				"/xrpc/com.atproto.server.createSession",
+				"/xrpc/com.atproto.repo.createRecord",
+				"/xrpc/com.atproto.repo.listRecords",
+			}, pdsPaths)
+		}
+	})
+
+	t.Run("Given an identity resolver, When GetRepo is called, Then it should be sent to the PDS of the account", func(t *testing.T) {
+		pdsPaths, entrywayPaths = nil, nil
+		lazuliClient := NewClient(entryway.URL, "", WithIdentityResolver(resolver))
+
+		body, err := lazuliClient.GetRepo(context.Background(), "did:plc:alice", "")
+		assert.NoError(t, err)
+		_ = body.Close()
+
+		assert.Empty(t, entrywayPaths)
+		assert.Equal(t, []string{"/xrpc/com.atproto.sync.getRepo"}, pdsPaths)
+
+		_, err = lazuliClient.GetRepo(context.Background(), "did:plc:unknown", "")
+		assert.Equal(t, &Error{Code: http.StatusBadRequest, Message: "fail to resolve identifier", Details: "identity: handle not found: did:plc:unknown"}, err)
+	})
+
+	t.Run("Given an identity resolver, When CreateSession is called with an email, Then it should log in at the URL given to the client", func(t *testing.T) {
+		entrywayPaths = nil
+		lazuliClient := NewClient(entryway.URL, "", WithIdentityResolver(resolver))
+
+		_, err := lazuliClient.CreateSession(context.Background(), "alice@example.com", "password")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/com.atproto.server.createSession"}, entrywayPaths)
+	})
+
+	t.Run("Given an identity resolver, When CreateSession is called with an identifier that cannot be resolved, Then it should return an error", func(t *testing.T) {
+		lazuliClient := NewClient("", "", WithIdentityResolver(resolver))
+
+		_, err := lazuliClient.CreateSession(context.Background(), "unknown.test", "password")
+		assert.Equal(t, &Error{Code: http.StatusBadRequest, Message: "fail to resolve identifier", Details: "identity: handle not found: unknown.test"}, err)
+
+		_, err = lazuliClient.CreateSession(context.Background(), "bob.test", "password")
+		assert.Equal(t, &Error{Code: http.StatusBadRequest, Message: "fail to resolve identifier", Details: "did:plc:bob has no pds endpoint"}, err)
+	})
+}
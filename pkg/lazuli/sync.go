@@ -0,0 +1,156 @@
+package lazuli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/mst"
+)
+
+// GetRepo downloads the repository of did from com.atproto.sync.getRepo as a CAR file, which must be closed by the
+// caller. It is downloaded from the PDS of did when an IdentityResolver is configured. When since is a revision, only
+// the blocks changed after it are sent; otherwise the whole repository is. As large repositories may take longer to
+// download than the timeout of the HTTP client, the download is only bounded by ctx.
+func (c *client) GetRepo(ctx context.Context, did, since string) (io.ReadCloser, error) {
+	query := url.Values{"did": {did}}
+	if since != "" {
+		query.Set("since", since)
+	}
+	serviceURL, err := c.accountURL(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	reqURL := fmt.Sprintf("%s/com.atproto.sync.getRepo?%s", serviceURL, query.Encode())
+
+	req, err := c.newRequest(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to create get repo request struct", err.Error())
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.car")
+
+	resp, err := c.downloadClient().Do(req)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to do request to get repo", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(resp, "get repo request failed")
+	}
+
+	return resp.Body, nil
+}
+
+// downloadClient returns a copy of the HTTP client without its timeout, which also covers reading the response body.
+func (c *client) downloadClient() *http.Client {
+	if c.httpClient.Timeout == 0 {
+		return c.httpClient
+	}
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	return &httpClient
+}
+
+// ForEachRecord downloads the whole repository of did and calls fn for each of its records, decoded with
+// bsky.DecodeRecord. Records are sorted by collection and then by record key, and only the records of the given
+// collections are visited when any is given. It stops at the first error returned by fn and returns it.
+func (c *client) ForEachRecord(ctx context.Context, did string, fn func(record bsky.RepoRecord) error, collections ...string) error {
+	body, err := c.GetRepo(ctx, did, "")
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	archive, err := car.ReadAll(body)
+	if err != nil {
+		return newError(http.StatusInternalServerError, "fail to read repo", err.Error())
+	}
+	if len(archive.Roots) == 0 {
+		return newError(http.StatusInternalServerError, "fail to read repo", "repo has no commit")
+	}
+	tree, err := mst.LoadCommit(archive, archive.Roots[0])
+	if err != nil {
+		return newError(http.StatusInternalServerError, "fail to read repo", err.Error())
+	}
+
+	var fnErr error
+	walkErr := tree.Walk(func(key string, value cid.CID) error {
+		if err := ctx.Err(); err != nil {
+			fnErr = err
+			return err
+		}
+
+		collection, rkey, _ := strings.Cut(key, "/")
+		if len(collections) > 0 && !slices.Contains(collections, collection) {
+			return nil
+		}
+
+		data, ok := archive.Get(value)
+		if !ok {
+			return fmt.Errorf("record %s of %s not found in repo", value, key)
+		}
+		record, err := bsky.DecodeRecord(collection, data)
+		if err != nil {
+			return fmt.Errorf("fail to decode record %s: %w", key, err)
+		}
+
+		fnErr = fn(bsky.RepoRecord{Collection: collection, RKey: rkey, CID: value, Record: record})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if walkErr != nil {
+		return newError(http.StatusInternalServerError, "fail to read repo", walkErr.Error())
+	}
+
+	return nil
+}
+
+// ListRepos lists a page of the repositories hosted by the server given to NewClient, such as a relay, with
+// com.atproto.sync.listRepos. The cursor of the response is given to get the next page, and limit is the size of the
+// page, between 1 and 1000, or 0 for the server default.
+func (c *client) ListRepos(ctx context.Context, cursor string, limit int) (*bsky.ListReposResponse, error) {
+	if limit < 0 || limit > 1000 {
+		return nil, newError(http.StatusBadRequest, "invalid limit query param", "limit must be between 1 and 1000")
+	}
+
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	reqURL := fmt.Sprintf("%s/com.atproto.sync.listRepos?%s", c.xrpcURL, query.Encode())
+
+	req, err := c.newRequest(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to create list repos request struct", err.Error())
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to do request to list repos", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(resp, "list repos request failed")
+	}
+
+	var listResponse bsky.ListReposResponse
+	if err := json.NewDecoder(resp.Body).Decode(&listResponse); err != nil {
+		return nil, newError(http.StatusInternalServerError, "fail to decode list repos response", err.Error())
+	}
+
+	return &listResponse, nil
+}
@@ -0,0 +1,373 @@
+package lazuli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/bsky"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/car"
+	"github.com/augustoasilva/go-lazuli/pkg/lazuli/cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repoCAR builds the CAR export of a repository holding the given records, keyed by "collection/rkey", in a tree of
+// a single node. Records left out of blocks are referenced by the tree but not included in the file.
+func repoCAR(t *testing.T, keys []string, records map[string]any, missing ...string) []byte {
+	encode := func(v any) car.Block {
+		data, err := cid.DagCBOR.Marshal(v)
+		require.NoError(t, err)
+		return car.Block{CID: cid.Sum(cid.CodecDagCBOR, data), Data: data}
+	}
+
+	var blocks []car.Block
+	entries := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		record := encode(records[key])
+		entries = append(entries, map[string]any{"p": 0, "k": []byte(key), "v": record.CID, "t": nil})
+		if !assert.ObjectsAreEqual(missing, []string{key}) {
+			blocks = append(blocks, record)
+		}
+	}
+	node := encode(map[string]any{"l": nil, "e": entries})
+	commit := encode(map[string]any{"did": "did:plc:test", "version": 3, "data": node.CID, "rev": "3kabc", "prev": nil})
+
+	var buf bytes.Buffer
+	w, err := car.NewWriter(&buf, commit.CID)
+	require.NoError(t, err)
+	for _, block := range append([]car.Block{commit, node}, blocks...) {
+		require.NoError(t, w.Write(block))
+	}
+	return buf.Bytes()
+}
+
+func TestClient_GetRepo(t *testing.T) {
+	type in struct {
+		did   string
+		since string
+	}
+
+	type out struct {
+		body  string
+		query string
+		err   error
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		timeout time.Duration
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a GetRepo function call, When the repository is found, Then it should stream the CAR file",
+			in:   in{did: "did:plc:test"},
+			out:  out{body: "car file", query: "did=did%3Aplc%3Atest"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("car file"))
+			},
+		},
+		{
+			name: "Given a GetRepo function call with a revision, When the repository is found, Then it should ask for the changes since it",
+			in:   in{did: "did:plc:test", since: "3kabc"},
+			out:  out{body: "car file", query: "did=did%3Aplc%3Atest&since=3kabc"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("car file"))
+			},
+		},
+		{
+			name:    "Given a GetRepo function call, When the download takes longer than the client timeout, Then it should stream the whole CAR file",
+			in:      in{did: "did:plc:test"},
+			out:     out{body: "car file", query: "did=did%3Aplc%3Atest"},
+			timeout: 50 * time.Millisecond,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("car "))
+				w.(http.Flusher).Flush()
+				time.Sleep(100 * time.Millisecond)
+				_, _ = w.Write([]byte("file"))
+			},
+		},
+		{
+			name: "Given a GetRepo function call, When the repository is not found, Then it should return an error",
+			in:   in{did: "did:plc:unknown"},
+			out: out{
+				query: "did=did%3Aplc%3Aunknown",
+				err:   newError(http.StatusBadRequest, "get repo request failed", `{"error":"RepoNotFound"}`),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"RepoNotFound"}`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.sync.getRepo", r.URL.Path)
+				assert.Equal(t, "application/vnd.ipld.car", r.Header.Get("Accept"))
+				query = r.URL.RawQuery
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			httpClient := server.Client()
+			httpClient.Timeout = tt.timeout
+			lazuliClient := &client{xrpcURL: server.URL, httpClient: httpClient}
+
+			body, err := lazuliClient.GetRepo(context.Background(), tt.in.did, tt.in.since)
+
+			assert.Equal(t, tt.out.query, query)
+			if tt.out.err != nil {
+				assert.Nil(t, body)
+				assert.Equal(t, tt.out.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			defer body.Close()
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.body, string(data))
+		})
+	}
+}
+
+func TestClient_ForEachRecord(t *testing.T) {
+	keys := []string{"app.bsky.feed.like/1", "app.bsky.feed.post/2", "com.example.record/3"}
+	records := map[string]any{
+		keys[0]: map[string]any{"$type": bsky.CollectionLike, "createdAt": "2024-01-01T00:00:00Z"},
+		keys[1]: map[string]any{"$type": bsky.CollectionPost, "text": "hello", "createdAt": "2024-01-01T00:00:00Z"},
+		keys[2]: map[string]any{"$type": "com.example.record", "value": "custom"},
+	}
+	stop := errors.New("stop")
+
+	type in struct {
+		collections []string
+		fnErr       error
+		cancel      bool
+	}
+
+	type out struct {
+		keys   []string
+		err    error
+		errMsg string
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a ForEachRecord function call, When the repository is downloaded, Then it should visit every record in order",
+			in:   in{},
+			out:  out{keys: keys},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(repoCAR(t, keys, records))
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call with collections, When the repository is downloaded, Then it should visit only their records",
+			in:   in{collections: []string{bsky.CollectionPost}},
+			out:  out{keys: keys[1:2]},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(repoCAR(t, keys, records))
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call, When the function returns an error, Then it should stop and return it",
+			in:   in{fnErr: stop},
+			out:  out{keys: keys[:1], err: stop},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(repoCAR(t, keys, records))
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call, When a record is missing from the repository, Then it should return an error",
+			in:   in{},
+			out: out{
+				keys:   keys[:1],
+				errMsg: "of app.bsky.feed.post/2 not found in repo",
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(repoCAR(t, keys, records, keys[1]))
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call, When the context is canceled while reading, Then it should return the context error",
+			in:   in{cancel: true},
+			out:  out{keys: keys[:1], err: context.Canceled},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(repoCAR(t, keys, records))
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call, When the response is not a CAR file, Then it should return an error",
+			in:   in{},
+			out:  out{errMsg: "status: 500, error: fail to read repo, details: car: fail to read header: unexpected EOF"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte{0x01})
+			},
+		},
+		{
+			name: "Given a ForEachRecord function call, When the request fails, Then it should return the request error",
+			in:   in{},
+			out:  out{errMsg: `status: 400, error: get repo request failed, details: {"error":"RepoNotFound"}`},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"RepoNotFound"}`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			lazuliClient := &client{xrpcURL: server.URL, httpClient: server.Client()}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var visited []string
+			err := lazuliClient.ForEachRecord(ctx, "did:plc:test", func(record bsky.RepoRecord) error {
+				visited = append(visited, record.Collection+"/"+record.RKey)
+				if tt.in.cancel {
+					cancel()
+				}
+				assert.True(t, record.CID.Defined())
+				switch record.Collection {
+				case bsky.CollectionPost:
+					assert.Equal(t, "hello", record.Record.(bsky.PostRecord).Text)
+				case bsky.CollectionLike:
+					assert.IsType(t, bsky.LikeRecord{}, record.Record)
+				default:
+					assert.Equal(t, "custom", record.Record.(bsky.UnknownRecord).Data["value"])
+				}
+				return tt.in.fnErr
+			}, tt.in.collections...)
+
+			assert.Equal(t, tt.out.keys, visited)
+			switch {
+			case tt.out.errMsg != "":
+				assert.ErrorContains(t, err, tt.out.errMsg)
+			case tt.out.err != nil:
+				assert.ErrorIs(t, err, tt.out.err)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_ListRepos(t *testing.T) {
+	active := false
+	head := cid.Sum(cid.CodecDagCBOR, []byte("commit"))
+
+	type in struct {
+		cursor string
+		limit  int
+	}
+
+	type out struct {
+		response *bsky.ListReposResponse
+		query    string
+		err      error
+	}
+
+	tests := []struct {
+		name    string
+		in      in
+		out     out
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Given a ListRepos function call, When there is a page of repositories, Then it should return it with the next cursor",
+			in:   in{cursor: "100", limit: 2},
+			out: out{
+				query: "cursor=100&limit=2",
+				response: &bsky.ListReposResponse{
+					Cursor: "102",
+					Repos: []bsky.ListedRepo{
+						{DID: "did:plc:first", Head: head, Rev: "3kabc"},
+						{DID: "did:plc:second", Head: head, Rev: "3kxyz", Active: &active, Status: "deactivated"},
+					},
+				},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"cursor": "102",
+					"repos": []map[string]any{
+						{"did": "did:plc:first", "head": head.String(), "rev": "3kabc"},
+						{"did": "did:plc:second", "head": head.String(), "rev": "3kxyz", "active": false, "status": "deactivated"},
+					},
+				})
+			},
+		},
+		{
+			name: "Given a ListRepos function call without cursor, When it is the last page, Then it should return no cursor",
+			out: out{
+				response: &bsky.ListReposResponse{Repos: []bsky.ListedRepo{}},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"repos":[]}`))
+			},
+		},
+		{
+			name: "Given a ListRepos function call, When the limit is too large, Then it should return an error",
+			in:   in{limit: 1001},
+			out:  out{err: newError(http.StatusBadRequest, "invalid limit query param", "limit must be between 1 and 1000")},
+		},
+		{
+			name: "Given a ListRepos function call, When the request fails, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "list repos request failed", `{"error":"InternalServerError"}`),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":"InternalServerError"}`))
+			},
+		},
+		{
+			name: "Given a ListRepos function call, When the response is not json, Then it should return an error",
+			out: out{
+				err: newError(http.StatusInternalServerError, "fail to decode list repos response", "invalid character 'o' in literal null (expecting 'u')"),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`not json`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/com.atproto.sync.listRepos", r.URL.Path)
+				query = r.URL.RawQuery
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			lazuliClient := &client{xrpcURL: server.URL, httpClient: server.Client()}
+
+			response, err := lazuliClient.ListRepos(context.Background(), tt.in.cursor, tt.in.limit)
+
+			assert.Equal(t, tt.out.query, query)
+			assert.Equal(t, tt.out.err, err)
+			assert.Equal(t, tt.out.response, response)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package tid generates and parses timestamp identifiers (TIDs), the sortable record keys used by most AT Protocol
+// collections, and validates the syntax of record keys.
+package tid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidTID is returned when a string is not a valid TID.
+	ErrInvalidTID = errors.New("tid: invalid tid")
+	// ErrInvalidClockID is returned when a clock identifier does not fit in the 10 bits of a TID.
+	ErrInvalidClockID = errors.New("tid: invalid clock id")
+	// ErrInvalidRecordKey is returned when a string is not a valid record key.
+	ErrInvalidRecordKey = errors.New("tid: invalid record key")
+)
+
+const (
+	// alphabet is the sortable base32 alphabet of TIDs.
+	alphabet = "234567abcdefghijklmnopqrstuvwxyz"
+	// length is the number of characters of a TID.
+	length = 13
+	// MaxClockID is the highest clock identifier of a TID.
+	MaxClockID = 1<<10 - 1
+
+	// maxRecordKeyLength is the maximum number of characters of a record key.
+	maxRecordKeyLength = 512
+)
+
+// TID is a timestamp identifier: a 64-bit integer holding the microseconds since the Unix epoch and a 10-bit clock
+// identifier, encoded with a sortable base32 alphabet so TIDs sort in time order.
+type TID string
+
+// New returns the TID of t with the given clock identifier, which must not be higher than MaxClockID.
+func New(t time.Time, clockID uint) (TID, error) {
+	if clockID > MaxClockID {
+		return "", fmt.Errorf("%w: %d", ErrInvalidClockID, clockID)
+	}
+	return fromInteger(uint64(t.UnixMicro())<<10 | uint64(clockID)), nil
+}
+
+// Parse checks that s is a valid TID and returns it.
+func Parse(s string) (TID, error) {
+	if len(s) != length {
+		return "", fmt.Errorf("%w: %q must have %d characters", ErrInvalidTID, s, length)
+	}
+	// the first character holds the highest bit of the integer, which must be zero
+	if strings.IndexByte(alphabet[:16], s[0]) < 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTID, s)
+	}
+	for i := 1; i < length; i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidTID, s)
+		}
+	}
+	return TID(s), nil
+}
+
+// Integer returns the integer encoded by the TID.
+func (t TID) Integer() uint64 {
+	var v uint64
+	for i := 0; i < len(t); i++ {
+		v = v<<5 | uint64(strings.IndexByte(alphabet, t[i]))
+	}
+	return v
+}
+
+// Time returns the time the TID was generated at, with microsecond precision.
+func (t TID) Time() time.Time {
+	return time.UnixMicro(int64(t.Integer() >> 10)).UTC()
+}
+
+// ClockID returns the identifier of the clock that generated the TID.
+func (t TID) ClockID() uint {
+	return uint(t.Integer() & MaxClockID)
+}
+
+func (t TID) String() string {
+	return string(t)
+}
+
+func fromInteger(v uint64) TID {
+	var b [length]byte
+	for i := length - 1; i >= 0; i-- {
+		b[i] = alphabet[v&31]
+		v >>= 5
+	}
+	return TID(b[:])
+}
+
+// Clock generates TIDs that always increase, even when the system clock goes back or when several TIDs are generated
+// within the same microsecond. Generators running at the same time on different machines should use different clock
+// identifiers so their TIDs do not collide.
+type Clock struct {
+	clockID uint64
+	now     func() time.Time
+
+	mu   sync.Mutex
+	last uint64
+}
+
+// NewClock creates a TID generator with the given clock identifier, which must not be higher than MaxClockID.
+func NewClock(clockID uint) (*Clock, error) {
+	if clockID > MaxClockID {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidClockID, clockID)
+	}
+	return &Clock{clockID: uint64(clockID), now: time.Now}, nil
+}
+
+// NewRandomClock creates a TID generator with a random clock identifier.
+func NewRandomClock() *Clock {
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	return &Clock{clockID: uint64(binary.BigEndian.Uint16(b[:]) & MaxClockID), now: time.Now}
+}
+
+// Next returns a TID of the current time, higher than every TID returned before by the clock.
+func (c *Clock) Next() TID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	micros := uint64(c.now().UnixMicro())
+	if micros <= c.last {
+		micros = c.last + 1
+	}
+	c.last = micros
+
+	return fromInteger(micros<<10 | c.clockID)
+}
+
+var defaultClock = NewRandomClock()
+
+// Next returns a TID of the current time from a clock shared by the process, with a random clock identifier.
+func Next() TID {
+	return defaultClock.Next()
+}
+
+// ValidateRecordKey checks the syntax of a record key: 1 to 512 characters among letters, digits and ".-_:~", except
+// "." and "..".
+func ValidateRecordKey(rkey string) error {
+	if rkey == "" || len(rkey) > maxRecordKeyLength {
+		return fmt.Errorf("%w: %q must have between 1 and %d characters", ErrInvalidRecordKey, rkey, maxRecordKeyLength)
+	}
+	if rkey == "." || rkey == ".." {
+		return fmt.Errorf("%w: %q", ErrInvalidRecordKey, rkey)
+	}
+	for i := 0; i < len(rkey); i++ {
+		c := rkey[i]
+		valid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(".-_:~", c) >= 0
+		if !valid {
+			return fmt.Errorf("%w: %q has invalid character %q", ErrInvalidRecordKey, rkey, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package tid
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	type in struct {
+		t       time.Time
+		clockID uint
+	}
+
+	type out struct {
+		tid TID
+		err error
+	}
+
+	tests := []struct {
+		name string
+		in   in
+		out  out
+	}{
+		{
+			name: "Given the Unix epoch and the clock 0, When New is called, Then it should return the lowest TID",
+			in:   in{t: time.UnixMicro(0)},
+			out:  out{tid: "2222222222222"},
+		},
+		{
+			name: "Given a time and a clock, When New is called, Then it should encode them",
+			in:   in{t: time.UnixMicro(1), clockID: MaxClockID},
+			out:  out{tid: "22222222223zz"},
+		},
+		{
+			name: "Given a clock identifier higher than 10 bits, When New is called, Then it should return ErrInvalidClockID",
+			in:   in{t: time.Now(), clockID: MaxClockID + 1},
+			out:  out{err: ErrInvalidClockID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tid, err := New(tt.in.t, tt.in.clockID)
+			if tt.out.err != nil {
+				assert.ErrorIs(t, err, tt.out.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.out.tid, tid)
+			assert.Equal(t, tt.in.t.UTC(), tid.Time())
+			assert.Equal(t, tt.in.clockID, tid.ClockID())
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  bool
+	}{
+		{name: "Given a valid TID, When it is parsed, Then it should be accepted", in: "3jzfcijpj2z2a"},
+		{name: "Given the highest TID, When it is parsed, Then it should be accepted", in: "jzzzzzzzzzzzz"},
+		{name: "Given a TID with the highest bit set, When it is parsed, Then it should be rejected", in: "kzzzzzzzzzzzz", err: true},
+		{name: "Given a TID with an invalid character, When it is parsed, Then it should be rejected", in: "3jzfcijpj2z21", err: true},
+		{name: "Given a TID with upper case characters, When it is parsed, Then it should be rejected", in: "3JZFCIJPJ2Z2A", err: true},
+		{name: "Given a TID of the wrong length, When it is parsed, Then it should be rejected", in: "3jzfcijpj2z2", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tid, err := Parse(tt.in)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidTID)
+				assert.Empty(t, tid)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.in, tid.String())
+		})
+	}
+
+	t.Run("Given a generated TID, When it is parsed, Then it should return its time and clock", func(t *testing.T) {
+		now := time.Date(2024, 9, 1, 12, 30, 15, 123456000, time.UTC)
+		generated, err := New(now, 42)
+		require.NoError(t, err)
+
+		tid, err := Parse(generated.String())
+		require.NoError(t, err)
+		assert.Equal(t, now, tid.Time())
+		assert.Equal(t, uint(42), tid.ClockID())
+	})
+}
+
+func TestClock_Next(t *testing.T) {
+	t.Run("Given a clock that does not move or goes back, When TIDs are generated, Then they should always increase", func(t *testing.T) {
+		c, err := NewClock(7)
+		require.NoError(t, err)
+		now := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+		c.now = func() time.Time { return now }
+
+		first := c.Next()
+		second := c.Next()
+		now = now.Add(-time.Second)
+		third := c.Next()
+
+		assert.Less(t, first.String(), second.String())
+		assert.Less(t, second.String(), third.String())
+		assert.Equal(t, now.Add(time.Second+2*time.Microsecond), third.Time())
+		assert.Equal(t, uint(7), third.ClockID())
+	})
+
+	t.Run("Given concurrent callers, When TIDs are generated, Then they should be unique", func(t *testing.T) {
+		c := NewRandomClock()
+		var mu sync.Mutex
+		seen := map[TID]bool{}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					tid := c.Next()
+					mu.Lock()
+					seen[tid] = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, seen, 800)
+	})
+
+	t.Run("Given the shared clock, When a TID is generated, Then it should be valid and recent", func(t *testing.T) {
+		tid := Next()
+
+		_, err := Parse(tid.String())
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), tid.Time(), time.Minute)
+	})
+
+	t.Run("Given a clock identifier higher than 10 bits, When a clock is created, Then it should return ErrInvalidClockID", func(t *testing.T) {
+		c, err := NewClock(MaxClockID + 1)
+		assert.ErrorIs(t, err, ErrInvalidClockID)
+		assert.Nil(t, c)
+	})
+}
+
+func TestValidateRecordKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  bool
+	}{
+		{name: "Given a TID, When it is validated, Then it should be accepted", in: "3jzfcijpj2z2a"},
+		{name: "Given a literal key, When it is validated, Then it should be accepted", in: "self"},
+		{name: "Given a key with every allowed symbol, When it is validated, Then it should be accepted", in: "example.com:Key_1-a~b"},
+		{name: "Given a key of 512 characters, When it is validated, Then it should be accepted", in: strings.Repeat("a", 512)},
+		{name: "Given an empty key, When it is validated, Then it should be rejected", in: "", err: true},
+		{name: "Given a key of 513 characters, When it is validated, Then it should be rejected", in: strings.Repeat("a", 513), err: true},
+		{name: "Given a dot, When it is validated, Then it should be rejected", in: ".", err: true},
+		{name: "Given two dots, When it is validated, Then it should be rejected", in: "..", err: true},
+		{name: "Given a key with a slash, When it is validated, Then it should be rejected", in: "a/b", err: true},
+		{name: "Given a key with a space, When it is validated, Then it should be rejected", in: "a b", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRecordKey(tt.in)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidRecordKey)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}